@@ -4,19 +4,38 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 
+	gh "github.com/google/go-github/v68/github"
 	"github.com/spf13/cobra"
 	"github.com/stympy/forkwatch/internal/analysis"
 	ghclient "github.com/stympy/forkwatch/internal/github"
 	"github.com/stympy/forkwatch/internal/output"
+	"github.com/stympy/forkwatch/internal/state"
 )
 
 var (
-	minAhead int
-	limit    int
-	jsonOut  bool
-	patchOut bool
+	minAhead    int
+	limit       int
+	concurrency int
+	depth       int
+	network     bool
+	refresh     bool
+	resume      bool
+	timeout     time.Duration
+	deadline    string
+	branches    []string
+	excludePRs  bool
+	fuzz        int
+	strict      bool
+	similarity  float64
+	consensus   float64
+	jsonOut     bool
+	patchOut    bool
 )
 
 var analyzeCmd = &cobra.Command{
@@ -30,6 +49,19 @@ var analyzeCmd = &cobra.Command{
 func init() {
 	analyzeCmd.Flags().IntVar(&minAhead, "min-ahead", 1, "Minimum commits ahead to consider")
 	analyzeCmd.Flags().IntVar(&limit, "limit", 100, "Max forks to analyze (sorted by most recently pushed)")
+	analyzeCmd.Flags().IntVar(&concurrency, "concurrency", 4, "Number of forks to compare in parallel")
+	analyzeCmd.Flags().IntVar(&depth, "depth", 1, "Levels of forks-of-forks to walk (0 for no limit)")
+	analyzeCmd.Flags().BoolVar(&network, "network", false, "Walk the whole fork network from its root, not just forks of owner/repo")
+	analyzeCmd.Flags().BoolVar(&refresh, "refresh", false, "Re-compare every fork, ignoring comparisons saved by earlier runs")
+	analyzeCmd.Flags().BoolVar(&resume, "resume", false, "Continue an interrupted run of the same repository from its checkpoint")
+	analyzeCmd.Flags().DurationVar(&timeout, "timeout", 0, "Stop comparing after this long and report partial results (e.g. 30m)")
+	analyzeCmd.Flags().StringVar(&deadline, "deadline", "", "Stop comparing at this time (RFC 3339) and report partial results")
+	analyzeCmd.Flags().StringSliceVar(&branches, "branches", nil, "Also compare fork branches matching these glob patterns (or \"all\")")
+	analyzeCmd.Flags().BoolVar(&excludePRs, "exclude-pr-submitted", false, "Hide forks whose change is already in an upstream pull request")
+	analyzeCmd.Flags().IntVar(&fuzz, "fuzz", 2, "Lines of context a hunk may ignore when checking that a patch applies (like patch --fuzz)")
+	analyzeCmd.Flags().BoolVar(&strict, "strict-grouping", false, "Group only byte-identical patches, not ones differing in line numbers, context or whitespace")
+	analyzeCmd.Flags().Float64Var(&similarity, "similarity", 0.5, "How alike (0-1) different patches must be to show as one family of variants; 0 to turn off")
+	analyzeCmd.Flags().Float64Var(&consensus, "consensus", 0, "Recommend only the hunks at least this share (0-1) of forks agree on, instead of whole patches")
 	analyzeCmd.Flags().BoolVar(&jsonOut, "json", false, "Output as JSON")
 	analyzeCmd.Flags().BoolVar(&patchOut, "patch", false, "Output a unified diff suitable for git apply")
 	analyzeCmd.MarkFlagsMutuallyExclusive("json", "patch")
@@ -42,54 +74,122 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("repository must be in owner/repo format")
 	}
 	owner, repo := parts[0], parts[1]
+	if concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
+	}
+	if depth < 0 {
+		return fmt.Errorf("--depth must not be negative")
+	}
+	if fuzz < 0 {
+		return fmt.Errorf("--fuzz must not be negative")
+	}
+	if similarity < 0 || similarity > 1 {
+		return fmt.Errorf("--similarity must be between 0 and 1")
+	}
+	if consensus < 0 || consensus > 1 {
+		return fmt.Errorf("--consensus must be between 0 and 1")
+	}
+	if resume && cacheDir == "" {
+		return fmt.Errorf("--resume needs a cache directory to read the checkpoint from")
+	}
 
-	ctx := context.Background()
+	ctx, cancel, err := analysisContext()
+	if err != nil {
+		return err
+	}
+	defer cancel()
 
-	client, err := ghclient.NewClient(ctx)
+	client, err := ghclient.NewClient(ctx, clientOptions())
 	if err != nil {
 		return err
 	}
 
-	fmt.Fprintf(os.Stderr, "Fetching forks of %s/%s...\n", owner, repo)
+	sched := ghclient.NewScheduler(10)
+	sched.OnPause = func(until time.Time) {
+		fmt.Fprintf(os.Stderr, "Rate limit nearly exhausted, pausing until %s...\n", until.Format("15:04:05"))
+	}
 
-	forks, upstream, err := ghclient.FetchForks(ctx, client, owner, repo, limit)
+	var cp *state.Checkpoint
+	if resume {
+		cp, err = state.OpenCheckpoint(state.CheckpointPath(filepath.Join(cacheDir, "checkpoints"), owner, repo))
+		if err != nil {
+			return err
+		}
+		// Carry on with the options the interrupted run started with.
+		minAhead = cp.Header.MinAhead
+		branches = cp.Header.Branches
+		fmt.Fprintf(os.Stderr, "Resuming analysis of %s/%s: %d of %d forks already compared\n",
+			owner, repo, len(cp.Done), len(cp.Header.Forks))
+	} else {
+		cp, err = startAnalysis(ctx, cmd, client, sched, owner, repo)
+		if err != nil || cp == nil {
+			return err
+		}
+	}
+
+	c := &comparer{
+		client:         client,
+		sched:          sched,
+		checkpoint:     cp,
+		owner:          owner,
+		repo:           repo,
+		upstreamBranch: cp.Header.UpstreamBranch,
+	}
+
+	c.store, err = openState(ctx, client, sched, owner, repo, c.upstreamBranch)
 	if err != nil {
 		return err
 	}
 
-	if len(forks) == 0 {
-		fmt.Println("No active forks found.")
-		return nil
+	run := c.compareAll(ctx, cp.Header.Forks)
+	if ctx.Err() == nil {
+		c.fetchCommitDetails(ctx, run.comparisons)
 	}
 
-	fmt.Fprintf(os.Stderr, "Found %d active forks, comparing to upstream...\n", len(forks))
-
-	upstreamBranch := upstream.GetDefaultBranch()
-	if upstreamBranch == "" {
-		upstreamBranch = "main"
+	if c.store != nil {
+		if err := c.store.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+	switch {
+	case run.complete:
+		cp.Remove()
+	case run.notReached > 0:
+		cp.Close()
+		fmt.Fprintf(os.Stderr, "Interrupted with %d forks not reached; run again with --resume to finish\n", run.notReached)
+	default:
+		cp.Close()
+		fmt.Fprintf(os.Stderr, "Some forks could not be compared yet; run again with --resume to retry them\n")
 	}
 
-	var comparisons []*ghclient.ForkComparison
-	for i, fork := range forks {
-		fmt.Fprintf(os.Stderr, "Analyzing fork %d/%d: %s...\n", i+1, len(forks), fork.Owner)
+	result := analysis.Cluster(run.comparisons, owner, repo, cp.Header.TotalForks, analysis.Options{
+		StrictGrouping: strict,
+		Similarity:     similarity,
+		Consensus:      consensus,
+	})
+	result.Skipped = run.skipped
+	result.NotReached = run.notReached
 
-		comp, err := ghclient.CompareFork(ctx, client, owner, repo, upstreamBranch, fork)
+	// Linking pull requests and checking upstream are niceties; an
+	// interrupted run skips them.
+	if ctx.Err() == nil {
+		fmt.Fprintf(os.Stderr, "Checking upstream pull requests...\n")
+		prs, err := ghclient.ListPullRequests(ctx, client, sched, owner, repo)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "  Warning: %v\n", err)
-			continue
-		}
-		if comp == nil {
-			continue
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		} else {
+			analysis.LinkPullRequests(result, prs, excludePRs)
 		}
-		if comp.AheadBy < minAhead {
-			continue
+
+		fmt.Fprintf(os.Stderr, "Checking changes against upstream...\n")
+		err = analysis.CheckUpstream(result, func(path string) (string, bool, error) {
+			return ghclient.FileContent(ctx, client, sched, owner, repo, c.upstreamBranch, path)
+		}, fuzz)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 		}
-		comparisons = append(comparisons, comp)
 	}
 
-	totalForks := upstream.GetForksCount()
-	result := analysis.Cluster(comparisons, owner, repo, totalForks)
-
 	if jsonOut {
 		return output.PrintJSON(result)
 	}
@@ -101,3 +201,129 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 	output.PrintTable(result)
 	return nil
 }
+
+// analysisContext returns a context canceled by SIGINT/SIGTERM or when
+// --timeout or --deadline is reached. After the first signal, default signal
+// handling is restored so a second Ctrl-C exits immediately.
+func analysisContext() (context.Context, context.CancelFunc, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	cancel := stop
+	if timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, timeout)
+		cancel = func() { cancelTimeout(); stop() }
+	}
+	if deadline != "" {
+		t, err := time.Parse(time.RFC3339, deadline)
+		if err != nil {
+			cancel()
+			return nil, nil, fmt.Errorf("--deadline must be an RFC 3339 time such as 2026-01-02T15:04:05Z: %w", err)
+		}
+		var cancelDeadline context.CancelFunc
+		ctx, cancelDeadline = context.WithDeadline(ctx, t)
+		prev := cancel
+		cancel = func() { cancelDeadline(); prev() }
+	}
+	return ctx, cancel, nil
+}
+
+// startAnalysis enumerates the forks to compare and records them in a new
+// checkpoint. It returns nil if there are no forks worth comparing.
+func startAnalysis(ctx context.Context, cmd *cobra.Command, client *gh.Client, sched *ghclient.Scheduler, owner, repo string) (*state.Checkpoint, error) {
+	fmt.Fprintf(os.Stderr, "Fetching forks of %s/%s...\n", owner, repo)
+
+	fetchOpts := ghclient.FetchOptions{Limit: limit, Depth: depth, Network: network}
+	if network && !cmd.Flags().Changed("depth") {
+		fetchOpts.Depth = 0
+	}
+
+	forks, upstream, err := ghclient.FetchForks(ctx, client, sched, owner, repo, fetchOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	forks = skipInactive(forks)
+	if len(forks) == 0 {
+		fmt.Println("No active forks found.")
+		return nil, nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Found %d active forks, comparing to upstream...\n", len(forks))
+
+	upstreamBranch := upstream.GetDefaultBranch()
+	if upstreamBranch == "" {
+		upstreamBranch = "main"
+	}
+
+	header := state.CheckpointHeader{
+		Owner:          owner,
+		Repo:           repo,
+		UpstreamBranch: upstreamBranch,
+		TotalForks:     upstream.GetForksCount(),
+		MinAhead:       minAhead,
+		Branches:       branches,
+		Forks:          forks,
+	}
+	if cacheDir == "" {
+		// Without a cache directory there's nowhere to checkpoint to; keep
+		// the run in memory only.
+		return state.NewMemoryCheckpoint(header), nil
+	}
+	return state.CreateCheckpoint(state.CheckpointPath(filepath.Join(cacheDir, "checkpoints"), owner, repo), header)
+}
+
+// skipInactive drops forks that can't be compared or, when enumeration
+// already reported ahead/behind counts, aren't far enough ahead of upstream.
+// The counts are for the default branch only, so with --branches they don't
+// rule a fork out.
+func skipInactive(forks []ghclient.ForkInfo) []ghclient.ForkInfo {
+	var active []ghclient.ForkInfo
+	for _, f := range forks {
+		if f.Disabled || (len(branches) == 0 && f.HasCounts && f.AheadBy < minAhead) {
+			continue
+		}
+		active = append(active, f)
+	}
+	return active
+}
+
+// openState loads the comparisons saved by earlier runs and discards those
+// invalidated by upstream changes. It returns nil when caching is disabled.
+func openState(ctx context.Context, client *gh.Client, sched *ghclient.Scheduler, owner, repo, upstreamBranch string) (*state.Store, error) {
+	if noCache || cacheDir == "" {
+		return nil, nil
+	}
+
+	store, err := state.Load(state.Path(filepath.Join(cacheDir, "state"), owner, repo))
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := ghclient.BranchHead(ctx, client, sched, owner, repo, upstreamBranch)
+	if err != nil {
+		return nil, err
+	}
+
+	branchSpec := strings.Join(branches, ",")
+	switch {
+	case refresh || store.UpstreamBranch != upstreamBranch || store.UpstreamHead == "" || store.Branches != branchSpec:
+		store.Reset()
+		store.UpstreamBranch = upstreamBranch
+		store.UpstreamHead = head
+		store.Branches = branchSpec
+	case store.UpstreamHead != head:
+		landed, err := ghclient.CommitsBetween(ctx, client, sched, owner, repo, store.UpstreamHead, head)
+		if err != nil {
+			// Upstream history was rewritten or is too long to inspect;
+			// start over rather than trust stale merge bases.
+			store.Reset()
+		}
+		store.Rebase(head, landed)
+	}
+	return store, nil
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	ghclient "github.com/stympy/forkwatch/internal/github"
+)
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Inspect GitHub authentication",
+}
+
+var authStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show which credentials forkwatch uses",
+	Long:  `Resolves credentials the same way analyze does and reports where the token came from, who it belongs to, its scopes and remaining rate limit.`,
+	Args:  cobra.NoArgs,
+	RunE:  runAuthStatus,
+}
+
+func init() {
+	authCmd.AddCommand(authStatusCmd)
+	rootCmd.AddCommand(authCmd)
+}
+
+func runAuthStatus(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	// Bypass the cache so the reported rate limit is current.
+	opts := clientOptions()
+	opts.CacheDir = ""
+
+	creds, err := ghclient.ResolveCredentials(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	opts.Tokens = creds.Tokens
+	client, err := ghclient.NewClient(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	info, err := ghclient.InspectToken(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Host:       %s\n", opts.Host())
+	fmt.Printf("Source:     %s\n", creds.Source)
+	if len(creds.Tokens) > 1 {
+		fmt.Printf("Tokens:     %d (rate limit below is their combined quota)\n", len(creds.Tokens))
+	}
+	if info.Login != "" {
+		fmt.Printf("User:       %s\n", info.Login)
+	}
+	scopes := info.Scopes
+	if scopes == "" {
+		scopes = "(none reported)"
+	}
+	fmt.Printf("Scopes:     %s\n", scopes)
+	fmt.Printf("Rate limit: %d/%d remaining\n", info.Remaining, info.Limit)
+	return nil
+}
@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	gh "github.com/google/go-github/v68/github"
+	"github.com/stympy/forkwatch/internal/analysis"
+	ghclient "github.com/stympy/forkwatch/internal/github"
+	"github.com/stympy/forkwatch/internal/state"
+)
+
+// comparer holds what the comparison workers share.
+type comparer struct {
+	client     *gh.Client
+	sched      *ghclient.Scheduler
+	store      *state.Store      // comparisons from earlier runs; nil when caching is disabled
+	checkpoint *state.Checkpoint // progress of this run
+
+	owner, repo, upstreamBranch string
+}
+
+// comparisonRun is the outcome of comparing a run's forks.
+type comparisonRun struct {
+	comparisons []*ghclient.ForkComparison
+	skipped     []analysis.SkippedFork
+	notReached  int  // forks not compared because the run was interrupted
+	complete    bool // false if --resume has forks left to retry
+}
+
+// compareAll compares forks against upstream using a bounded pool of
+// workers. Forks already recorded in the checkpoint are not compared again,
+// and forks unchanged since the last run reuse their stored comparisons.
+// Results keep the order of forks regardless of completion order so that
+// clustering output is stable between runs. Forks that fail even after
+// retries are reported as skipped, with the reason. Once ctx is canceled no
+// further forks are started, and those not compared are counted as not reached;
+// forks already in the checkpoint are still included.
+func (c *comparer) compareAll(ctx context.Context, forks []ghclient.ForkInfo) comparisonRun {
+	records := make([]state.CheckpointRecord, len(forks))
+	started := make([]bool, len(forks))
+	jobs := make(chan int)
+
+	// Workers add to the checkpoint as they go; dispatch from a copy of
+	// what earlier runs recorded.
+	previous := make(map[int]state.CheckpointRecord, len(c.checkpoint.Done))
+	for i, rec := range c.checkpoint.Done {
+		previous[i] = rec
+	}
+
+	var mu sync.Mutex
+	done, reused := len(previous), 0
+
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fork := forks[i]
+				if c.store != nil {
+					if entry, ok := c.store.Lookup(fork); ok {
+						records[i] = state.CheckpointRecord{Index: i, Comparisons: entry.Comparisons}
+						mu.Lock()
+						reused++
+						mu.Unlock()
+						continue
+					}
+				}
+
+				comps, err := c.compareFork(ctx, fork)
+				rec := state.CheckpointRecord{Index: i, Comparisons: comps}
+				if err != nil {
+					rec.Reason = ghclient.FailureReason(err)
+					rec.Error = err.Error()
+				}
+				records[i] = rec
+				if rec.Reason == "canceled" {
+					continue
+				}
+
+				mu.Lock()
+				done++
+				fmt.Fprintf(os.Stderr, "Analyzed fork %d/%d: %s\n", done, len(forks), fork.Owner)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "  Warning: %v\n", err)
+				}
+				mu.Unlock()
+
+				if err == nil && c.store != nil {
+					c.store.Record(fork, comps)
+				}
+				if err == nil || !retryable(rec.Reason) {
+					if err := c.checkpoint.Record(rec); err != nil {
+						fmt.Fprintf(os.Stderr, "  Warning: %v\n", err)
+					}
+				}
+			}
+		}()
+	}
+
+	canceled := false
+	for i := range forks {
+		if rec, ok := previous[i]; ok {
+			records[i] = rec
+			started[i] = true
+			continue
+		}
+		if canceled {
+			continue
+		}
+		select {
+		case jobs <- i:
+			started[i] = true
+		case <-ctx.Done():
+			canceled = true
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if reused > 0 {
+		fmt.Fprintf(os.Stderr, "Reused saved comparisons for %d forks not pushed to since the last run\n", reused)
+	}
+
+	run := comparisonRun{complete: true}
+	for i, rec := range records {
+		switch {
+		case !started[i] || rec.Reason == "canceled":
+			run.notReached++
+			run.complete = false
+		case rec.Reason != "":
+			run.skipped = append(run.skipped, analysis.SkippedFork{
+				Owner:  forks[i].Owner,
+				Reason: rec.Reason,
+				Error:  rec.Error,
+			})
+			if retryable(rec.Reason) {
+				run.complete = false
+			}
+		default:
+			for _, comp := range rec.Comparisons {
+				if comp.AheadBy < minAhead {
+					continue
+				}
+				run.comparisons = append(run.comparisons, comp)
+			}
+		}
+	}
+	return run
+}
+
+// fetchCommitDetails fetches the patch-ids and files of the commits of
+// forks that change a file another fork changes too, which lineages and
+// origins need; other forks' commits are never fetched. Comparisons that
+// already have them, e.g. from an earlier run, are left alone. A fork whose
+// commits can't be fetched is only linked to others by shared SHAs.
+func (c *comparer) fetchCommitDetails(ctx context.Context, comps []*ghclient.ForkComparison) {
+	var pending []*ghclient.ForkComparison
+	for _, comp := range analysis.Overlapping(comps) {
+		if !comp.Detailed {
+			pending = append(pending, comp)
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Fetching commits of %d forks with overlapping changes...\n", len(pending))
+
+	jobs := make(chan *ghclient.ForkComparison)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for comp := range jobs {
+				if err := ghclient.CommitDetails(ctx, c.client, c.sched, comp); err != nil && ctx.Err() == nil {
+					mu.Lock()
+					fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, comp := range pending {
+		jobs <- comp
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// compareFork compares the fork's default branch and, with --branches, each
+// other matching branch. Only branches with meaningful changes are returned.
+func (c *comparer) compareFork(ctx context.Context, fork ghclient.ForkInfo) ([]*ghclient.ForkComparison, error) {
+	names := []string{fork.DefaultBranch}
+	if len(branches) > 0 {
+		all, err := ghclient.ListBranches(ctx, c.client, c.sched, fork)
+		if err != nil {
+			return nil, err
+		}
+		names = ghclient.SelectBranches(fork, all, branches)
+	}
+
+	var comps []*ghclient.ForkComparison
+	for _, name := range names {
+		comp, err := ghclient.CompareBranch(ctx, c.client, c.sched, c.owner, c.repo, c.upstreamBranch, fork, name)
+		if err != nil {
+			return nil, err
+		}
+		if comp != nil {
+			comps = append(comps, comp)
+		}
+	}
+	return comps, nil
+}
+
+// retryable reports whether a fork skipped for reason may succeed if
+// compared again later, so it is left out of the checkpoint for --resume.
+func retryable(reason string) bool {
+	switch reason {
+	case "canceled", "rate limited", "secondary rate limit", "server error":
+		return true
+	}
+	return false
+}
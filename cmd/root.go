@@ -2,8 +2,18 @@ package cmd
 
 import (
 	"os"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
+	ghclient "github.com/stympy/forkwatch/internal/github"
+)
+
+var (
+	cacheDir  string
+	noCache   bool
+	apiURL    string
+	uploadURL string
+	authOpts  ghclient.AuthOptions
 )
 
 var rootCmd = &cobra.Command{
@@ -12,6 +22,41 @@ var rootCmd = &cobra.Command{
 	Long:  `Forkwatch analyzes GitHub repository forks to find meaningful changes that haven't been submitted as pull requests. It groups forks by the files they modify and highlights convergence — when multiple independent forks touch the same code.`,
 }
 
+func init() {
+	rootCmd.PersistentFlags().StringVar(&cacheDir, "cache-dir", defaultCacheDir(), "Directory for cached GitHub API responses")
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "Disable the on-disk HTTP cache")
+	rootCmd.PersistentFlags().StringVar(&apiURL, "api-url", "", "GitHub Enterprise Server API URL (defaults to $GH_HOST when set)")
+	rootCmd.PersistentFlags().StringVar(&uploadURL, "upload-url", "", "GitHub Enterprise Server upload URL (defaults to --api-url)")
+	rootCmd.PersistentFlags().StringVar(&authOpts.TokenFile, "token-file", "", "Read the GitHub token from this file")
+	rootCmd.PersistentFlags().Int64Var(&authOpts.AppID, "app-id", 0, "Authenticate as this GitHub App")
+	rootCmd.PersistentFlags().Int64Var(&authOpts.AppInstallationID, "app-installation-id", 0, "GitHub App installation to authenticate as")
+	rootCmd.PersistentFlags().StringVar(&authOpts.AppKeyFile, "app-key-file", "", "GitHub App private key (PEM)")
+}
+
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "forkwatch")
+}
+
+// clientOptions builds GitHub client options from the global flags.
+func clientOptions() ghclient.ClientOptions {
+	opts := ghclient.ClientOptions{
+		BaseURL:   apiURL,
+		UploadURL: uploadURL,
+		Auth:      authOpts,
+	}
+	if host := os.Getenv("GH_HOST"); opts.BaseURL == "" && host != "" && host != "github.com" {
+		opts.BaseURL = "https://" + host + "/api/v3/"
+	}
+	if !noCache && cacheDir != "" {
+		opts.CacheDir = filepath.Join(cacheDir, "http")
+	}
+	return opts
+}
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
@@ -0,0 +1,356 @@
+package analysis
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stympy/forkwatch/internal/diff"
+	gh "github.com/stympy/forkwatch/internal/github"
+)
+
+// numbered returns n lines "line 0", "line 1", ..., with edits replacing
+// lines by index.
+func numbered(n int, edits map[int]string) string {
+	var lines []string
+	for i := 0; i < n; i++ {
+		line := fmt.Sprintf("line %d", i)
+		if s, ok := edits[i]; ok {
+			line = s
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// insertAt inserts line before the zero-based line i of text.
+func insertAt(text string, i int, line string) string {
+	lines := strings.SplitAfter(text, "\n")
+	return strings.Join(lines[:i], "") + line + "\n" + strings.Join(lines[i:], "")
+}
+
+// fork returns a fork of its own lineage changing file f from old to new.
+func fork(owner, old, new string) ForkSummary {
+	return ForkSummary{Owner: owner, Lineage: owner, Status: "modified", Patch: diff.Compute("f", old, new, 3)}
+}
+
+// comparison returns a comparison of a fork changing file f from old to new.
+func comparison(owner, old, new string) *gh.ForkComparison {
+	return &gh.ForkComparison{
+		Fork:       gh.ForkInfo{Owner: owner, DefaultBranch: "main"},
+		Branch:     "main",
+		CommitSHAs: []string{owner + "-sha"},
+		FilesChanged: []gh.FileChange{{
+			Filename: "f",
+			Status:   "modified",
+			Patch:    diff.Compute("f", old, new, 3).Body(),
+		}},
+	}
+}
+
+func TestClusterHunks(t *testing.T) {
+	base := numbered(60, nil)
+	tests := []struct {
+		name       string
+		forks      []ForkSummary
+		convergent int // clusters made by both forks
+	}{
+		{
+			"same change",
+			[]ForkSummary{fork("a", base, numbered(60, map[int]string{20: "x"})), fork("b", base, numbered(60, map[int]string{20: "x"}))},
+			1,
+		},
+		{
+			"same change shifted",
+			[]ForkSummary{
+				fork("a", base, numbered(60, map[int]string{20: "x"})),
+				fork("b", insertAt(base, 0, "new"), insertAt(numbered(60, map[int]string{20: "x"}), 0, "new")),
+			},
+			1,
+		},
+		{
+			"same line in different places",
+			[]ForkSummary{fork("a", base, insertAt(base, 10, "}")), fork("b", base, insertAt(base, 40, "}"))},
+			0,
+		},
+		{
+			"same line in the same place, too far apart",
+			[]ForkSummary{
+				fork("a", base, numbered(60, map[int]string{20: "x"})),
+				fork("b", numbered(30, nil)+base, numbered(30, nil)+numbered(60, map[int]string{20: "x"})),
+			},
+			0,
+		},
+		{
+			"different changes",
+			[]ForkSummary{fork("a", base, numbered(60, map[int]string{20: "x"})), fork("b", base, numbered(60, map[int]string{20: "y"}))},
+			0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			convergent := 0
+			for _, h := range clusterHunks(tt.forks) {
+				if h.Convergence == 2 {
+					convergent++
+				}
+			}
+			if convergent != tt.convergent {
+				t.Errorf("%d convergent hunks, want %d", convergent, tt.convergent)
+			}
+		})
+	}
+}
+
+func TestAssembleHunks(t *testing.T) {
+	base := numbered(60, nil)
+	agreed := map[int]string{5: "five", 9: "nine"}
+	forks := []ForkSummary{
+		fork("a", base, numbered(60, agreed)),
+		fork("b", base, numbered(60, map[int]string{5: "five", 9: "nine", 40: "forty"})),
+		fork("c", base, numbered(60, map[int]string{9: "nine", 50: "fifty"})),
+	}
+	hunks := clusterHunks(forks)
+
+	group := assembleHunks("f", hunks, 2)
+	if group == nil {
+		t.Fatal("no hunks assembled")
+	}
+	if want := diff.Compute("f", base, numbered(60, agreed), 3).String(); group.Patch.String() != want {
+		t.Errorf("assembled patch =\n%s\nwant\n%s", group.Patch, want)
+	}
+	if labels := forkLabels(group.Forks); !reflect.DeepEqual(labels, []string{"a", "b", "c"}) {
+		t.Errorf("agreeing forks = %v", labels)
+	}
+	assembled := 0
+	for _, h := range hunks {
+		if h.Assembled {
+			assembled++
+		}
+	}
+	if assembled != 2 {
+		t.Errorf("%d hunks marked assembled, want 2", assembled)
+	}
+
+	if group := assembleHunks("f", clusterHunks(forks), 3); group == nil || len(group.Patch.Hunks) != 1 {
+		t.Errorf("with 3 votes, want only the change all three make")
+	}
+	if group := assembleHunks("f", clusterHunks(forks), 4); group != nil {
+		t.Errorf("with 4 votes, assembled %s", group.Patch)
+	}
+}
+
+func TestRecommend(t *testing.T) {
+	base := numbered(60, nil)
+	tests := []struct {
+		name  string
+		comps []*gh.ForkComparison
+		opts  Options
+		want  int
+	}{
+		{
+			"same patch",
+			[]*gh.ForkComparison{
+				comparison("a", base, numbered(60, map[int]string{20: "x"})),
+				comparison("b", base, numbered(60, map[int]string{20: "x"})),
+			},
+			Options{}, 1,
+		},
+		{
+			"shared hunk only",
+			[]*gh.ForkComparison{
+				comparison("a", base, numbered(60, map[int]string{20: "x", 50: "a"})),
+				comparison("b", base, numbered(60, map[int]string{20: "x", 5: "b"})),
+			},
+			Options{}, 1,
+		},
+		{
+			"same line in different places",
+			[]*gh.ForkComparison{
+				comparison("a", base, insertAt(base, 10, "}")),
+				comparison("b", base, insertAt(base, 40, "}")),
+			},
+			Options{}, 0,
+		},
+		{
+			"below consensus",
+			[]*gh.ForkComparison{
+				comparison("a", base, numbered(60, map[int]string{20: "x"})),
+				comparison("b", base, numbered(60, map[int]string{20: "x"})),
+				comparison("c", base, numbered(60, map[int]string{30: "y"})),
+				comparison("d", base, numbered(60, map[int]string{40: "z"})),
+			},
+			Options{Consensus: 0.75}, 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recs := Recommend(Cluster(tt.comps, "up", "repo", len(tt.comps), tt.opts))
+			if len(recs) != tt.want {
+				t.Fatalf("%d recommendations, want %d", len(recs), tt.want)
+			}
+		})
+	}
+}
+
+func TestLineages(t *testing.T) {
+	comp := func(owner string, shas, ids []string) *gh.ForkComparison {
+		return &gh.ForkComparison{Fork: gh.ForkInfo{Owner: owner}, CommitSHAs: shas, PatchIDs: ids}
+	}
+	tests := []struct {
+		name  string
+		comps []*gh.ForkComparison
+		want  map[string]string
+	}{
+		{
+			"unrelated",
+			[]*gh.ForkComparison{comp("a", []string{"1"}, nil), comp("b", []string{"2"}, nil)},
+			map[string]string{"a": "a", "b": "b"},
+		},
+		{
+			"shared commit",
+			[]*gh.ForkComparison{comp("b", []string{"1"}, nil), comp("a", []string{"2", "1"}, nil)},
+			map[string]string{"a": "a", "b": "a"},
+		},
+		{
+			"cherry-picked",
+			[]*gh.ForkComparison{comp("a", []string{"1"}, []string{"p"}), comp("b", []string{"2"}, []string{"p"})},
+			map[string]string{"a": "a", "b": "a"},
+		},
+		{
+			"unknown patch-ids",
+			[]*gh.ForkComparison{comp("a", []string{"1"}, []string{""}), comp("b", []string{"2"}, []string{""})},
+			map[string]string{"a": "a", "b": "b"},
+		},
+		{
+			"transitive",
+			[]*gh.ForkComparison{
+				comp("c", []string{"1"}, nil),
+				comp("b", []string{"1", "2"}, nil),
+				comp("a", []string{"2"}, nil),
+			},
+			map[string]string{"a": "a", "b": "a", "c": "a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lineages(tt.comps); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lineages = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileCommits(t *testing.T) {
+	comp := &gh.ForkComparison{
+		CommitSHAs:  []string{"1", "2", "3"},
+		CommitFiles: [][]string{{"f"}, {"g"}, nil},
+	}
+	if got := commitSHAs(fileCommits(comp, "f")); !reflect.DeepEqual(got, []string{"1"}) {
+		t.Errorf("commits for f = %v, want the one known to touch it", got)
+	}
+	if got := commitSHAs(fileCommits(comp, "h")); !reflect.DeepEqual(got, []string{"3"}) {
+		t.Errorf("commits for h = %v, want the one with unknown files", got)
+	}
+}
+
+func TestFindOrigin(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name   string
+		forks  []ForkSummary
+		author string
+		fork   string
+		copies map[string]string
+	}{
+		{
+			"earliest wins",
+			[]ForkSummary{
+				{Owner: "a", Commits: []Commit{{SHA: "1", Author: "a", Date: day(5)}}},
+				{Owner: "b", Commits: []Commit{{SHA: "2", Author: "b", Date: day(3)}}},
+			},
+			"b", "b", nil,
+		},
+		{
+			"inherited commit credits its author's fork",
+			[]ForkSummary{
+				{Owner: "a", Commits: []Commit{{SHA: "1", Author: "b", Date: day(3)}}},
+				{Owner: "b", Commits: []Commit{{SHA: "1", Author: "b", Date: day(3)}}},
+			},
+			"b", "b", map[string]string{"a": "b"},
+		},
+		{
+			"cherry-pick",
+			[]ForkSummary{
+				{Owner: "a", Commits: []Commit{{SHA: "1", PatchID: "p", Author: "a", Date: day(3)}}},
+				{Owner: "b", Branch: "fix", Commits: []Commit{{SHA: "2", PatchID: "p", Author: "a", Date: day(4)}}},
+			},
+			"a", "a", map[string]string{"b:fix": "a"},
+		},
+		{
+			"unknown dates",
+			[]ForkSummary{{Owner: "a", Commits: []Commit{{SHA: "1", Author: "a"}}}},
+			"", "", nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := findOrigin(tt.forks)
+			if tt.author == "" {
+				if o != nil {
+					t.Fatalf("origin = %+v, want none", o)
+				}
+				return
+			}
+			if o == nil {
+				t.Fatal("no origin")
+			}
+			if o.Author != tt.author || o.Fork != tt.fork || !reflect.DeepEqual(o.CopiedFrom, tt.copies) {
+				t.Errorf("origin = %s in %s, copied %v; want %s in %s, copied %v", o.Author, o.Fork, o.CopiedFrom, tt.author, tt.fork, tt.copies)
+			}
+		})
+	}
+}
+
+func TestGroupPatchesOrder(t *testing.T) {
+	base := numbered(60, nil)
+	branch := func(owner, name string, edits map[int]string) ForkSummary {
+		f := fork(owner, base, numbered(60, edits))
+		f.Branch = name
+		return f
+	}
+	forks := []ForkSummary{
+		branch("b", "two", map[int]string{30: "y"}),
+		branch("a", "main", map[int]string{20: "x"}),
+		branch("b", "one", map[int]string{10: "z"}),
+		branch("c", "main", map[int]string{20: "x"}),
+	}
+	want := [][]string{{"a:main", "c:main"}, {"b:one"}, {"b:two"}}
+	for run := 0; run < 5; run++ {
+		var got [][]string
+		for _, g := range GroupPatches(forks, Options{}).Groups {
+			got = append(got, forkLabels(g.Forks))
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("groups = %v, want %v", got, want)
+		}
+	}
+}
+
+func forkLabels(forks []ForkSummary) []string {
+	var labels []string
+	for _, f := range forks {
+		labels = append(labels, f.Label())
+	}
+	return labels
+}
+
+func commitSHAs(commits []Commit) []string {
+	var shas []string
+	for _, c := range commits {
+		shas = append(shas, c.SHA)
+	}
+	return shas
+}
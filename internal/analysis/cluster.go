@@ -3,24 +3,84 @@ package analysis
 import (
 	"sort"
 
+	"github.com/stympy/forkwatch/internal/diff"
 	gh "github.com/stympy/forkwatch/internal/github"
 )
 
 type FileCluster struct {
-	Filename    string
-	Forks       []ForkSummary
-	Convergence int            // number of independent forks touching this file
-	PatchGroups *PatchGrouping // nil for single-fork files
+	Filename       string
+	Forks          []ForkSummary
+	Convergence    int            // independent lineages touching this file
+	RawConvergence int            // distinct forks touching this file, related or not
+	PatchGroups    *PatchGrouping // nil for single-fork files
+	PullRequests   []LinkedPR     // upstream PRs carrying forks' changes to this file
+	Hunks          []HunkCluster  // single changes across forks, most convergent first
+	HunkGroup      *PatchGroup    // the changes forks agree on, assembled; nil if none
+}
+
+// patchGroups returns every candidate patch for the file: the whole-file
+// patch groups and the assembled hunks.
+func (c *FileCluster) patchGroups() []*PatchGroup {
+	var groups []*PatchGroup
+	if c.PatchGroups != nil {
+		for i := range c.PatchGroups.Groups {
+			groups = append(groups, &c.PatchGroups.Groups[i])
+		}
+	}
+	if c.HunkGroup != nil {
+		groups = append(groups, c.HunkGroup)
+	}
+	return groups
 }
 
 type ForkSummary struct {
 	Owner          string
+	Lineage        string // forks sharing commits have the same lineage
+	Branch         string
+	DefaultBranch  bool // whether Branch is the fork's default branch
 	HTMLURL        string
 	AheadBy        int
 	CommitMessages []string
+	Commits        []Commit // commits that may have changed this file
+	Status         string   // added, removed, modified, renamed, ...
+	Path           string   // path in the fork; differs from the cluster's for renames
 	Additions      int
 	Deletions      int
-	Patch          string
+	Patch          *diff.Patch // nil when GitHub returned no diff, e.g. for binary files
+	Recomputed     bool        // Patch was computed locally, not returned by GitHub
+}
+
+// Label identifies the fork in output: the owner, plus the branch when it
+// isn't the fork's default branch.
+func (f ForkSummary) Label() string {
+	if f.DefaultBranch || f.Branch == "" {
+		return f.Owner
+	}
+	return f.Owner + ":" + f.Branch
+}
+
+// countOwners counts distinct fork owners. Several branches of the same
+// fork touching a file are not independent, so they count once.
+func countOwners(forks []ForkSummary) int {
+	seen := make(map[string]bool)
+	for _, f := range forks {
+		seen[f.Owner] = true
+	}
+	return len(seen)
+}
+
+// SkippedFork is a fork that couldn't be compared, and why.
+type SkippedFork struct {
+	Owner  string
+	Reason string // short classification, e.g. "not found" or "server error"
+	Error  string
+}
+
+// Options tunes how forks' changes are grouped.
+type Options struct {
+	StrictGrouping bool    // group only byte-identical patches
+	Similarity     float64 // how alike patch groups must be to form a family; 0 for no families
+	Consensus      float64 // share of independent forks a hunk needs to be recommended; 0 to recommend whole patches
 }
 
 type AnalysisResult struct {
@@ -30,10 +90,21 @@ type AnalysisResult struct {
 	AnalyzedForks int
 	ActiveForks   int
 	Clusters      []FileCluster
+	Skipped       []SkippedFork
+	NotReached    int // forks not compared because the run was interrupted
+
+	opts Options
 }
 
-func Cluster(comparisons []*gh.ForkComparison, upstreamOwner, upstreamRepo string, totalForks int) *AnalysisResult {
+// Partial reports whether the run was interrupted before every fork was
+// compared.
+func (r *AnalysisResult) Partial() bool {
+	return r.NotReached > 0
+}
+
+func Cluster(comparisons []*gh.ForkComparison, upstreamOwner, upstreamRepo string, totalForks int, opts Options) *AnalysisResult {
 	fileMap := make(map[string][]ForkSummary)
+	lineage := lineages(comparisons)
 
 	for _, comp := range comparisons {
 		// Build per-file additions/deletions for this fork
@@ -45,44 +116,95 @@ func Cluster(comparisons []*gh.ForkComparison, upstreamOwner, upstreamRepo strin
 		for _, f := range comp.FilesChanged {
 			summary := ForkSummary{
 				Owner:          comp.Fork.Owner,
+				Lineage:        lineage[comp.Fork.Owner],
+				Branch:         comp.Branch,
+				DefaultBranch:  comp.Branch == comp.Fork.DefaultBranch,
 				HTMLURL:        comp.Fork.HTMLURL,
 				AheadBy:        comp.AheadBy,
 				CommitMessages: comp.CommitMessages,
+				Commits:        fileCommits(comp, f.SourcePath()),
 				Additions:      f.Additions,
 				Deletions:      f.Deletions,
-				Patch:          f.Patch,
+				Status:         f.Status,
+				Path:           f.Filename,
+				Patch:          f.Diff(),
+				Recomputed:     f.Recomputed,
 			}
-			fileMap[f.Filename] = append(fileMap[f.Filename], summary)
+			// Renamed files join the forks that edited them in place.
+			fileMap[f.SourcePath()] = append(fileMap[f.SourcePath()], summary)
 		}
 	}
 
 	var clusters []FileCluster
 	for filename, forks := range fileMap {
-		c := FileCluster{
-			Filename:    filename,
-			Forks:       forks,
-			Convergence: len(forks),
+		clusters = append(clusters, newFileCluster(filename, forks, opts))
+	}
+	sortClusters(clusters)
+
+	// lineage has one entry per fork, however many of its branches were
+	// compared.
+	return &AnalysisResult{
+		UpstreamOwner: upstreamOwner,
+		UpstreamRepo:  upstreamRepo,
+		TotalForks:    totalForks,
+		AnalyzedForks: len(lineage),
+		ActiveForks:   len(lineage),
+		Clusters:      clusters,
+		opts:          opts,
+	}
+}
+
+// Overlapping returns the comparisons that change a file another fork
+// changes too. Only their commits matter for lineages and origins, which
+// only come into play for files several forks touch.
+func Overlapping(comparisons []*gh.ForkComparison) []*gh.ForkComparison {
+	owners := make(map[string]map[string]bool)
+	for _, comp := range comparisons {
+		for _, f := range comp.FilesChanged {
+			path := f.SourcePath()
+			if owners[path] == nil {
+				owners[path] = make(map[string]bool)
+			}
+			owners[path][comp.Fork.Owner] = true
 		}
-		if c.Convergence >= 2 {
-			c.PatchGroups = GroupPatches(forks)
+	}
+
+	var shared []*gh.ForkComparison
+	for _, comp := range comparisons {
+		for _, f := range comp.FilesChanged {
+			if len(owners[f.SourcePath()]) >= 2 {
+				shared = append(shared, comp)
+				break
+			}
 		}
-		clusters = append(clusters, c)
 	}
+	return shared
+}
+
+func newFileCluster(filename string, forks []ForkSummary, opts Options) FileCluster {
+	c := FileCluster{
+		Filename:       filename,
+		Forks:          forks,
+		Convergence:    countLineages(forks),
+		RawConvergence: countOwners(forks),
+	}
+	if c.RawConvergence >= 2 {
+		c.PatchGroups = GroupPatches(forks, opts)
+		c.Hunks = clusterHunks(forks)
+		c.HunkGroup = assembleHunks(filename, c.Hunks, minVotes(c.Convergence, opts.Consensus))
+	}
+	return c
+}
 
-	// Sort: most convergent first, then alphabetically
+// sortClusters puts the most convergent first, then sorts alphabetically.
+func sortClusters(clusters []FileCluster) {
 	sort.Slice(clusters, func(i, j int) bool {
 		if clusters[i].Convergence != clusters[j].Convergence {
 			return clusters[i].Convergence > clusters[j].Convergence
 		}
+		if clusters[i].RawConvergence != clusters[j].RawConvergence {
+			return clusters[i].RawConvergence > clusters[j].RawConvergence
+		}
 		return clusters[i].Filename < clusters[j].Filename
 	})
-
-	return &AnalysisResult{
-		UpstreamOwner: upstreamOwner,
-		UpstreamRepo:  upstreamRepo,
-		TotalForks:    totalForks,
-		AnalyzedForks: len(comparisons),
-		ActiveForks:   len(comparisons),
-		Clusters:      clusters,
-	}
 }
@@ -0,0 +1,163 @@
+package analysis
+
+import (
+	"math"
+	"sort"
+
+	"github.com/stympy/forkwatch/internal/diff"
+)
+
+const (
+	// hunkContext is the context kept around each change when hunks are
+	// split for comparison.
+	hunkContext = 3
+
+	// hunkDrift is how far apart, in upstream lines, the same change may
+	// sit in different forks and still count as one. Forks branch off at
+	// different upstream commits, so line numbers shift between them.
+	hunkDrift = 20
+)
+
+// HunkCluster is one change to one region of a file, made by one or more
+// forks, whatever else they changed in the file.
+type HunkCluster struct {
+	Hunk           diff.Hunk // as the first fork made it
+	Forks          []ForkSummary
+	Convergence    int  // independent lineages making this change
+	RawConvergence int  // distinct forks making this change
+	Assembled      bool // part of the file's assembled hunk patch
+}
+
+// clusterHunks splits each fork's patch into single changes and groups
+// identical changes, in the same surrounding code, at about the same
+// upstream position.
+func clusterHunks(forks []ForkSummary) []HunkCluster {
+	type entry struct {
+		hunk diff.Hunk
+		fork ForkSummary
+	}
+	byKey := make(map[string][]entry)
+	var keys []string
+	for _, f := range forks {
+		if f.Patch == nil {
+			continue
+		}
+		for _, h := range f.Patch.Hunks {
+			for _, part := range h.Split(hunkContext) {
+				key := part.ChangeKey()
+				if _, ok := byKey[key]; !ok {
+					keys = append(keys, key)
+				}
+				byKey[key] = append(byKey[key], entry{part, f})
+			}
+		}
+	}
+
+	var clusters []HunkCluster
+	for _, key := range keys {
+		entries := byKey[key]
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].hunk.OldStart < entries[j].hunk.OldStart })
+		var current *HunkCluster
+		last := 0
+		for _, e := range entries {
+			if current == nil || e.hunk.OldStart-last > hunkDrift {
+				clusters = append(clusters, HunkCluster{Hunk: e.hunk})
+				current = &clusters[len(clusters)-1]
+			}
+			if !hasFork(current.Forks, e.fork) {
+				current.Forks = append(current.Forks, e.fork)
+			}
+			last = e.hunk.OldStart
+		}
+	}
+	for i := range clusters {
+		clusters[i].Convergence = countLineages(clusters[i].Forks)
+		clusters[i].RawConvergence = countOwners(clusters[i].Forks)
+	}
+
+	sort.SliceStable(clusters, func(i, j int) bool {
+		if clusters[i].Convergence != clusters[j].Convergence {
+			return clusters[i].Convergence > clusters[j].Convergence
+		}
+		return clusters[i].Hunk.OldStart < clusters[j].Hunk.OldStart
+	})
+	return clusters
+}
+
+func hasFork(forks []ForkSummary, f ForkSummary) bool {
+	for _, g := range forks {
+		if g.Label() == f.Label() {
+			return true
+		}
+	}
+	return false
+}
+
+// minVotes returns how many independent forks must make a hunk for it to be
+// assembled: the consensus share of the file's forks, and never fewer than
+// two.
+func minVotes(convergence int, consensus float64) int {
+	return max(2, int(math.Ceil(consensus*float64(convergence))))
+}
+
+// assembleHunks builds a patch from the changes at least votes independent
+// forks agree on, taking the most agreed first and leaving out any whose
+// changed lines overlap one already taken; changes close enough to share
+// context are merged into one hunk. The patch edits filename in place, or creates
+// it if every agreeing fork did; renames and mode changes are left out. It
+// marks the hunks it uses and returns nil if there are none.
+func assembleHunks(filename string, hunks []HunkCluster, votes int) *PatchGroup {
+	var chosen []diff.Hunk
+	var agreeing []ForkSummary
+	for i := range hunks {
+		h := &hunks[i]
+		if h.Convergence < votes || overlapsAny(h.Hunk, chosen) {
+			continue
+		}
+		h.Assembled = true
+		chosen = append(chosen, h.Hunk)
+		for _, f := range h.Forks {
+			if !hasFork(agreeing, f) {
+				agreeing = append(agreeing, f)
+			}
+		}
+	}
+	if len(chosen) == 0 {
+		return nil
+	}
+
+	base := &diff.Patch{OldPath: filename, NewPath: filename}
+	created := true
+	for _, f := range agreeing {
+		created = created && f.Status == "added"
+	}
+	if created {
+		base.OldPath, base.NewMode = "", agreeing[0].Patch.NewMode
+	}
+	return &PatchGroup{Patch: base.WithHunks(chosen), Forks: agreeing}
+}
+
+// overlapsAny reports whether h changes any line one of hunks changes.
+// Context doesn't count.
+func overlapsAny(h diff.Hunk, hunks []diff.Hunk) bool {
+	start, end := h.ChangedRange()
+	for _, other := range hunks {
+		otherStart, otherEnd := other.ChangedRange()
+		if start < otherEnd && otherStart < end {
+			return true
+		}
+	}
+	return false
+}
+
+// hunkAgreement returns how many independent forks agree on every hunk of
+// the assembled patch: the convergence of its least agreed hunk.
+func hunkAgreement(hunks []HunkCluster) int {
+	agreed := 0
+	for _, h := range hunks {
+		if h.Assembled && (agreed == 0 || h.Convergence < agreed) {
+			agreed = h.Convergence
+		}
+	}
+	return agreed
+}
@@ -0,0 +1,62 @@
+package analysis
+
+import gh "github.com/stympy/forkwatch/internal/github"
+
+// lineages groups forks that share history: a commit with the same SHA,
+// which a fork of a fork inherits, or the same patch-id, which a
+// cherry-pick keeps. Forks in one lineage are a single vote when counting
+// convergence. It maps each fork owner to its lineage, named after the
+// lineage's alphabetically first owner.
+func lineages(comparisons []*gh.ForkComparison) map[string]string {
+	parent := make(map[string]string)
+	var find func(owner string) string
+	find = func(owner string) string {
+		if p, ok := parent[owner]; ok && p != owner {
+			parent[owner] = find(p)
+			return parent[owner]
+		}
+		parent[owner] = owner
+		return owner
+	}
+	union := func(a, b string) {
+		ra, rb := find(a), find(b)
+		if rb < ra {
+			ra, rb = rb, ra
+		}
+		parent[rb] = ra
+	}
+
+	firstSeen := make(map[string]string) // commit SHA or patch-id -> owner
+	link := func(key, owner string) {
+		if other, ok := firstSeen[key]; ok {
+			union(other, owner)
+		} else {
+			firstSeen[key] = owner
+		}
+	}
+	for _, comp := range comparisons {
+		owner := comp.Fork.Owner
+		find(owner)
+		for i, sha := range comp.CommitSHAs {
+			link("sha:"+sha, owner)
+			if i < len(comp.PatchIDs) && comp.PatchIDs[i] != "" {
+				link("patch:"+comp.PatchIDs[i], owner)
+			}
+		}
+	}
+
+	result := make(map[string]string, len(parent))
+	for owner := range parent {
+		result[owner] = find(owner)
+	}
+	return result
+}
+
+// countLineages counts the independent lineages among forks.
+func countLineages(forks []ForkSummary) int {
+	seen := make(map[string]bool)
+	for _, f := range forks {
+		seen[f.Lineage] = true
+	}
+	return len(seen)
+}
@@ -0,0 +1,143 @@
+package analysis
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	gh "github.com/stympy/forkwatch/internal/github"
+)
+
+// Commit is a fork commit that may have changed a file.
+type Commit struct {
+	SHA     string
+	PatchID string
+	Author  string
+	Date    time.Time
+}
+
+// Origin credits the change a patch group shares to its first author.
+type Origin struct {
+	Author     string            // author of the earliest commit behind the change
+	Fork       string            // label of the fork holding that commit
+	Date       time.Time         // its author date
+	CopiedFrom map[string]string // fork label -> label of the fork it took the commit from
+}
+
+// fileCommits returns the commits of a comparison that may have changed
+// path: those known to touch it or, if none are, those whose files are
+// unknown, such as merges and commits too old to have been inspected.
+func fileCommits(comp *gh.ForkComparison, path string) []Commit {
+	var known, unknown []Commit
+	for i, sha := range comp.CommitSHAs {
+		filesKnown := i < len(comp.CommitFiles) && comp.CommitFiles[i] != nil
+		if filesKnown && !contains(comp.CommitFiles[i], path) {
+			continue
+		}
+		c := Commit{SHA: sha}
+		if i < len(comp.PatchIDs) {
+			c.PatchID = comp.PatchIDs[i]
+		}
+		if i < len(comp.CommitAuthors) {
+			c.Author = comp.CommitAuthors[i]
+		}
+		if i < len(comp.CommitDates) {
+			c.Date = comp.CommitDates[i]
+		}
+		if filesKnown {
+			known = append(known, c)
+		} else {
+			unknown = append(unknown, c)
+		}
+	}
+	if len(known) > 0 {
+		return known
+	}
+	return unknown
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// findOrigin works out which fork a shared change started in. The earliest
+// authored commit wins, preferring the fork of the commit's author on a
+// tie, e.g. for a commit other forks inherited. A fork that holds a commit
+// authored by another fork's owner, by SHA or patch-id, copied it from that
+// fork. It returns nil when the forks' commits are unknown.
+func findOrigin(forks []ForkSummary) *Origin {
+	var origin *Origin
+	var originOwn bool
+	for _, f := range forks {
+		for _, c := range f.Commits {
+			if c.Date.IsZero() {
+				continue
+			}
+			own := strings.EqualFold(c.Author, f.Owner)
+			if origin == nil || c.Date.Before(origin.Date) || c.Date.Equal(origin.Date) && own && !originOwn {
+				origin = &Origin{Author: c.Author, Fork: f.Label(), Date: c.Date}
+				originOwn = own
+			}
+		}
+	}
+	if origin == nil {
+		return nil
+	}
+
+	// authoredIn maps each commit, by SHA and patch-id, to the fork of the
+	// owner who wrote it.
+	authoredIn := make(map[string]string)
+	for _, f := range forks {
+		for _, c := range f.Commits {
+			if strings.EqualFold(c.Author, f.Owner) {
+				for _, key := range commitKeys(c) {
+					authoredIn[key] = f.Label()
+				}
+			}
+		}
+	}
+	for _, f := range forks {
+		for _, c := range f.Commits {
+			if source := copiedFrom(c, f.Label(), authoredIn); source != "" {
+				if origin.CopiedFrom == nil {
+					origin.CopiedFrom = make(map[string]string)
+				}
+				origin.CopiedFrom[f.Label()] = source
+				break
+			}
+		}
+	}
+	return origin
+}
+
+func copiedFrom(c Commit, label string, authoredIn map[string]string) string {
+	for _, key := range commitKeys(c) {
+		if source, ok := authoredIn[key]; ok && source != label {
+			return source
+		}
+	}
+	return ""
+}
+
+func commitKeys(c Commit) []string {
+	keys := []string{"sha:" + c.SHA}
+	if c.PatchID != "" {
+		keys = append(keys, "patch:"+c.PatchID)
+	}
+	return keys
+}
+
+// Copies lists the forks that copied the change, sorted.
+func (o *Origin) Copies() []string {
+	var copies []string
+	for label := range o.CopiedFrom {
+		copies = append(copies, label)
+	}
+	sort.Strings(copies)
+	return copies
+}
@@ -1,42 +1,95 @@
 package analysis
 
 import (
-	"fmt"
 	"sort"
-	"strings"
+
+	"github.com/stympy/forkwatch/internal/diff"
 )
 
 type PatchGroup struct {
-	Patch string        // cleaned diff for display
-	Full  string        // original patch text
-	Forks []ForkSummary // forks with this identical patch
+	Patch  *diff.Patch   // the shared patch; nil for a fork without one
+	Forks  []ForkSummary // forks with this identical patch
+	Origin *Origin       // who wrote the change first; nil for single-fork groups
+
+	AlreadyUpstream bool              // upstream has since made this change itself
+	Application     *diff.Application // how the patch applies to upstream today; nil if unchecked
+
+	Includes       []Inclusion // smaller groups whose whole change this patch makes too
+	PartialSupport int         // independent forks, not in this group, making part of its change
+}
+
+// Inclusion is a smaller patch group whose change another group's patch
+// contains.
+type Inclusion struct {
+	Group int // index into Groups
+	Extra int // changed lines the larger patch makes beyond it
 }
 
 type PatchGrouping struct {
-	Groups []PatchGroup // sorted largest-first
+	Groups   []PatchGroup  // sorted largest-first
+	Families []PatchFamily // groups making nearly the same change
+}
+
+// PatchFamily is two or more patch groups whose changes are similar but not
+// the same, such as forks bumping a dependency to different versions.
+type PatchFamily struct {
+	Members []int         // indices into Groups; the first, largest, is the representative
+	Core    []diff.Line   // changed lines every member makes
+	Extra   [][]diff.Line // per member, the changed lines it makes beyond Core
+}
+
+// Family returns the family group i belongs to, or nil.
+func (g *PatchGrouping) Family(i int) *PatchFamily {
+	for f := range g.Families {
+		for _, m := range g.Families[f].Members {
+			if m == i {
+				return &g.Families[f]
+			}
+		}
+	}
+	return nil
 }
 
-// GroupPatches groups forks by identical patch text. Empty patches are ungroupable
-// (each gets its own single-fork group).
-func GroupPatches(forks []ForkSummary) *PatchGrouping {
+// GroupPatches groups forks making the same change: the same patch
+// fingerprint, which ignores line numbers, context and whitespace, or with
+// opts.StrictGrouping, the byte-identical patch, headers included. Each fork
+// keeps its own patch; the group shows the variant most of its forks have.
+// Missing patches are ungroupable (each gets its own single-fork group).
+// Groups at least opts.Similarity alike form families.
+func GroupPatches(forks []ForkSummary, opts Options) *PatchGrouping {
 	grouped := make(map[string][]ForkSummary)
 	var ungrouped []ForkSummary
 
 	for _, f := range forks {
-		if f.Patch == "" {
+		if f.Patch.Empty() {
 			ungrouped = append(ungrouped, f)
-		} else {
-			grouped[f.Patch] = append(grouped[f.Patch], f)
+			continue
+		}
+		key := f.Patch.Fingerprint()
+		if opts.StrictGrouping {
+			key = f.Patch.String()
 		}
+		grouped[key] = append(grouped[key], f)
+	}
+
+	// Build groups in key order so ties sort the same way every run.
+	keys := make([]string, 0, len(grouped))
+	for key := range grouped {
+		keys = append(keys, key)
 	}
+	sort.Strings(keys)
 
 	var groups []PatchGroup
-	for patch, members := range grouped {
-		groups = append(groups, PatchGroup{
-			Patch: CleanDiff(patch),
-			Full:  patch,
+	for _, key := range keys {
+		members := grouped[key]
+		group := PatchGroup{
+			Patch: commonVariant(members),
 			Forks: members,
-		})
+		}
+		if len(members) > 1 {
+			group.Origin = findOrigin(members)
+		}
+		groups = append(groups, group)
 	}
 
 	// Each ungrouped fork gets its own group
@@ -46,36 +99,113 @@ func GroupPatches(forks []ForkSummary) *PatchGrouping {
 		})
 	}
 
-	// Sort: largest groups first, then by first fork owner for stability
-	sort.Slice(groups, func(i, j int) bool {
+	// Sort: largest groups first, then by first fork for stability; groups
+	// from branches of the same fork keep their key order.
+	sort.SliceStable(groups, func(i, j int) bool {
 		if len(groups[i].Forks) != len(groups[j].Forks) {
 			return len(groups[i].Forks) > len(groups[j].Forks)
 		}
-		return groups[i].Forks[0].Owner < groups[j].Forks[0].Owner
+		return groups[i].Forks[0].Label() < groups[j].Forks[0].Label()
 	})
 
-	return &PatchGrouping{Groups: groups}
+	findInclusions(groups)
+	return &PatchGrouping{Groups: groups, Families: families(groups, opts.Similarity)}
+}
+
+// findInclusions records, for each group, the groups whose change its patch
+// contains, and counts their forks as partial support.
+func findInclusions(groups []PatchGroup) {
+	for i := range groups {
+		g := &groups[i]
+		if g.Patch == nil {
+			continue
+		}
+		own := make(map[string]bool)
+		for _, f := range g.Forks {
+			own[f.Lineage] = true
+		}
+		var supporters []ForkSummary
+		for j, other := range groups {
+			if i == j || other.Patch == nil {
+				continue
+			}
+			extra, ok := diff.Contains(g.Patch, other.Patch)
+			if !ok {
+				continue
+			}
+			g.Includes = append(g.Includes, Inclusion{Group: j, Extra: extra})
+			for _, f := range other.Forks {
+				if !own[f.Lineage] {
+					supporters = append(supporters, f)
+				}
+			}
+		}
+		g.PartialSupport = countLineages(supporters)
+	}
 }
 
-// CleanDiff strips @@ hunk headers, keeping only +/- and context lines.
-func CleanDiff(patch string) string {
-	var lines []string
-	for _, line := range strings.Split(patch, "\n") {
-		if strings.HasPrefix(line, "@@") {
+// families gathers groups into families whose members are all at least
+// threshold alike. Groups are taken largest first, each joining the first
+// family it is close enough to, so every family is led by its largest group.
+// A threshold of 0 disables families.
+func families(groups []PatchGroup, threshold float64) []PatchFamily {
+	if threshold <= 0 {
+		return nil
+	}
+	var candidates [][]int
+	for i, g := range groups {
+		if g.Patch == nil {
+			continue
+		}
+		joined := false
+		for f, members := range candidates {
+			if alike(groups, members, g.Patch, threshold) {
+				candidates[f] = append(members, i)
+				joined = true
+				break
+			}
+		}
+		if !joined {
+			candidates = append(candidates, []int{i})
+		}
+	}
+
+	var fams []PatchFamily
+	for _, members := range candidates {
+		if len(members) < 2 {
 			continue
 		}
-		lines = append(lines, line)
+		var patches []*diff.Patch
+		for _, m := range members {
+			patches = append(patches, groups[m].Patch)
+		}
+		core, extra := diff.Core(patches)
+		fams = append(fams, PatchFamily{Members: members, Core: core, Extra: extra})
+	}
+	return fams
+}
+
+func alike(groups []PatchGroup, members []int, p *diff.Patch, threshold float64) bool {
+	for _, m := range members {
+		if diff.Similarity(groups[m].Patch, p) < threshold {
+			return false
+		}
 	}
-	return strings.TrimSpace(strings.Join(lines, "\n"))
+	return true
 }
 
-// TruncateDiff caps a diff at maxLines, appending a summary if truncated.
-func TruncateDiff(diff string, maxLines int) string {
-	lines := strings.Split(diff, "\n")
-	if len(lines) <= maxLines {
-		return diff
+// commonVariant returns the patch most of the forks have exactly, the
+// earliest listed on a tie.
+func commonVariant(forks []ForkSummary) *diff.Patch {
+	counts := make(map[string]int)
+	var best *diff.Patch
+	bestCount := 0
+	for _, f := range forks {
+		key := f.Patch.String()
+		counts[key]++
+		if counts[key] > bestCount {
+			best, bestCount = f.Patch, counts[key]
+		}
 	}
-	remaining := len(lines) - maxLines
-	truncated := strings.Join(lines[:maxLines], "\n")
-	return truncated + fmt.Sprintf("\n... (%d more lines)", remaining)
+	return best
 }
@@ -0,0 +1,116 @@
+package analysis
+
+import (
+	"strings"
+
+	"github.com/stympy/forkwatch/internal/diff"
+	gh "github.com/stympy/forkwatch/internal/github"
+)
+
+// prSimilarity is how close a pull request's patch to a file must be to a
+// fork's for the PR to count as carrying the fork's change.
+const prSimilarity = 0.6
+
+// LinkedPR is an upstream pull request carrying a change forks made.
+type LinkedPR struct {
+	Number int
+	Title  string
+	URL    string
+	Author string
+	State  string   // open, closed or merged
+	Forks  []string // labels of the forks whose change it carries
+}
+
+// LinkPullRequests annotates each cluster with the upstream pull requests
+// that carry its forks' changes: PRs opened from the fork's branch that
+// touch the file, and PRs whose patch to the file closely matches the
+// fork's. With excludeSubmitted, forks whose change is already in a PR are
+// dropped and the clusters recomputed without them.
+func LinkPullRequests(result *AnalysisResult, prs []gh.PullRequest, excludeSubmitted bool) {
+	var clusters []FileCluster
+	for _, c := range result.Clusters {
+		links := linkCluster(c, prs)
+		if excludeSubmitted && len(links) > 0 {
+			submitted := make(map[string]bool)
+			for _, pr := range links {
+				for _, label := range pr.Forks {
+					submitted[label] = true
+				}
+			}
+			var remaining []ForkSummary
+			for _, f := range c.Forks {
+				if !submitted[f.Label()] {
+					remaining = append(remaining, f)
+				}
+			}
+			if len(remaining) == 0 {
+				continue
+			}
+			c = newFileCluster(c.Filename, remaining, result.opts)
+		}
+		c.PullRequests = links
+		clusters = append(clusters, c)
+	}
+	sortClusters(clusters)
+	result.Clusters = clusters
+}
+
+func linkCluster(c FileCluster, prs []gh.PullRequest) []LinkedPR {
+	var links []LinkedPR
+	for _, pr := range prs {
+		file := prFile(pr, c.Filename)
+		if file == nil {
+			continue
+		}
+		patch := file.Diff()
+
+		var forks []string
+		for _, f := range c.Forks {
+			fromFork := strings.EqualFold(pr.HeadOwner, f.Owner) && pr.HeadBranch == f.Branch
+			if fromFork || patch != nil && f.Patch != nil && diff.Similarity(patch, f.Patch) >= prSimilarity {
+				forks = append(forks, f.Label())
+			}
+		}
+		if len(forks) == 0 {
+			continue
+		}
+		links = append(links, LinkedPR{
+			Number: pr.Number,
+			Title:  pr.Title,
+			URL:    pr.URL,
+			Author: pr.Author,
+			State:  pr.State,
+			Forks:  forks,
+		})
+	}
+	return links
+}
+
+// prFile returns the pull request's change to the upstream path, if any.
+func prFile(pr gh.PullRequest, path string) *gh.FileChange {
+	for i, f := range pr.Files {
+		if f.SourcePath() == path {
+			return &pr.Files[i]
+		}
+	}
+	return nil
+}
+
+// prsForForks returns the linked pull requests carrying any of the forks'
+// change.
+func prsForForks(links []LinkedPR, forks []ForkSummary) []LinkedPR {
+	labels := make(map[string]bool)
+	for _, f := range forks {
+		labels[f.Label()] = true
+	}
+	var matched []LinkedPR
+	for _, pr := range links {
+		for _, label := range pr.Forks {
+			if labels[label] {
+				matched = append(matched, pr)
+				break
+			}
+		}
+	}
+	return matched
+}
@@ -1,44 +1,98 @@
 package analysis
 
+import "github.com/stympy/forkwatch/internal/diff"
+
 // Recommendation is the top-voted change for a single file.
 type Recommendation struct {
-	File          string
-	Patch         string // raw patch from GitHub API (with @@ headers)
-	Convergence   int    // total forks touching this file
-	AgreedBy      int    // forks with this exact patch
-	Forks         []string
-	CommitMessage string // representative first-line commit message
+	File           string
+	Status         string // added, removed, modified, renamed, ...
+	Patch          *diff.Patch
+	Applies        diff.ApplyStatus // how Patch applies to upstream today; "" if unchecked
+	Applicable     *diff.Patch      // the hunks of Patch that apply, fitted to upstream; nil if none or unchecked
+	Convergence    int              // independent lineages touching this file
+	RawConvergence int              // distinct forks touching this file, related or not
+	AgreedBy       int              // independent lineages with this exact patch
+	Forks          []string
+	CommitMessage  string        // representative first-line commit message
+	PullRequests   []LinkedPR    // upstream PRs already carrying this change
+	HunkVotes      []HunkCluster // for a patch assembled hunk by hunk, every hunk forks made, Assembled if included
 }
 
 // Recommend returns the most-converged-upon patch for each convergent
-// cluster (convergence >= 2). The result is ordered by convergence
-// descending, matching the cluster sort order.
+// cluster (convergence >= 2), passing over changes upstream already has.
+// When no whole-file patch is shared by two independent forks, the
+// changes they do agree on are assembled hunk by hunk. With a consensus
+// share set, every recommendation is assembled that way, from the hunks
+// enough of the file's forks make.
+// The result is ordered by convergence descending, matching the cluster
+// sort order.
 func Recommend(result *AnalysisResult) []Recommendation {
 	var recs []Recommendation
 	for _, c := range result.Clusters {
 		if c.Convergence < 2 || c.PatchGroups == nil || len(c.PatchGroups.Groups) == 0 {
 			continue
 		}
-		top := c.PatchGroups.Groups[0]
-		if len(top.Forks) < 2 || top.Full == "" {
-			continue
+		top, ok := topPending(c.PatchGroups.Groups)
+		agreed := countLineages(top.Forks)
+		var status string
+		var votes []HunkCluster
+		if result.opts.Consensus > 0 || !ok || agreed < 2 || top.Patch.Empty() {
+			if c.HunkGroup == nil || c.HunkGroup.AlreadyUpstream {
+				continue
+			}
+			top, agreed, votes = *c.HunkGroup, hunkAgreement(c.Hunks), c.Hunks
+			status = "modified"
+			if top.Patch.IsNew() {
+				status = "added"
+			}
+		} else {
+			status = top.Forks[0].Status
 		}
 		var owners []string
 		var msg string
 		for _, f := range top.Forks {
-			owners = append(owners, f.Owner)
+			owners = append(owners, f.Label())
 			if msg == "" && len(f.CommitMessages) > 0 {
 				msg = f.CommitMessages[0]
 			}
 		}
-		recs = append(recs, Recommendation{
-			File:          c.Filename,
-			Patch:         top.Full,
-			Convergence:   c.Convergence,
-			AgreedBy:      len(top.Forks),
-			Forks:         owners,
-			CommitMessage: msg,
-		})
+		rec := Recommendation{
+			File:           c.Filename,
+			Status:         status,
+			Patch:          top.Patch,
+			Convergence:    c.Convergence,
+			RawConvergence: c.RawConvergence,
+			AgreedBy:       agreed,
+			Forks:          owners,
+			CommitMessage:  msg,
+			PullRequests:   prsForForks(c.PullRequests, top.Forks),
+			HunkVotes:      votes,
+		}
+		if a := top.Application; a != nil {
+			rec.Applies = a.Status
+			rec.Applicable = a.Patch
+		}
+		recs = append(recs, rec)
 	}
 	return recs
 }
+
+// topPending returns the largest patch group upstream doesn't have yet.
+func topPending(groups []PatchGroup) (PatchGroup, bool) {
+	for _, g := range groups {
+		if !g.AlreadyUpstream {
+			return g, true
+		}
+	}
+	return PatchGroup{}, false
+}
+
+// ApplicablePatch returns the part of the patch that applies to upstream
+// today, or the whole patch if that wasn't checked. It is nil when no hunk
+// applies.
+func (r Recommendation) ApplicablePatch() *diff.Patch {
+	if r.Applies == "" {
+		return r.Patch
+	}
+	return r.Applicable
+}
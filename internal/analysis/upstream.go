@@ -0,0 +1,60 @@
+package analysis
+
+import "github.com/stympy/forkwatch/internal/diff"
+
+// FileFetcher returns a file's current content upstream. found is false
+// when the file doesn't exist.
+type FileFetcher func(path string) (content string, found bool, err error)
+
+// CheckUpstream checks each patch group against the current upstream
+// version of its file: whether upstream has since made the change on its
+// own, which Recommend skips, and how well the patch still applies, with up
+// to fuzz lines of context ignored.
+func CheckUpstream(result *AnalysisResult, fetch FileFetcher, fuzz int) error {
+	type file struct {
+		content string
+		found   bool
+	}
+	files := make(map[string]file)
+
+	for i := range result.Clusters {
+		c := &result.Clusters[i]
+		for _, g := range c.patchGroups() {
+			if g.Patch == nil {
+				continue
+			}
+			// Clusters are keyed by the upstream path, which for a rename
+			// is the file's old name.
+			path := c.Filename
+			f, ok := files[path]
+			if !ok {
+				var err error
+				if f.content, f.found, err = fetch(path); err != nil {
+					return err
+				}
+				files[path] = f
+			}
+			g.AlreadyUpstream = alreadyApplied(g.Patch, f.content, f.found)
+			g.Application = g.Patch.Apply(f.content, f.found, fuzz)
+		}
+	}
+	return nil
+}
+
+// alreadyApplied reports whether upstream's current file, given by content
+// and found, already has the patch's change: its additions are all present
+// or it applies in reverse. Deleting, creating and renaming a file are
+// judged by whether the file exists.
+func alreadyApplied(p *diff.Patch, content string, found bool) bool {
+	switch {
+	case p.IsDeleted():
+		return !found
+	case !found:
+		return false
+	case len(p.Hunks) == 0:
+		// Nothing to compare but the file's existence; a mode change
+		// can't be checked.
+		return p.IsNew() || p.IsRename()
+	}
+	return p.AdditionsPresent(content) || p.ReverseApplies(content)
+}
@@ -0,0 +1,205 @@
+package diff
+
+import "strings"
+
+// ReverseApplies reports whether the patch could be applied in reverse to
+// content, i.e. whether content already has every hunk's result: the
+// hunk's context and added lines, in order, somewhere in the file. A
+// patch without hunks never matches.
+func (p *Patch) ReverseApplies(content string) bool {
+	if p == nil || len(p.Hunks) == 0 {
+		return false
+	}
+	lines := contentLines(content)
+	for _, h := range p.Hunks {
+		var want []string
+		for _, l := range h.Lines {
+			if l.Kind != Deleted {
+				want = append(want, l.Text)
+			}
+		}
+		if len(want) > 0 && findNear(lines, want, 0, 0) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// AdditionsPresent reports whether content already has what every hunk
+// adds: the added lines together with the hunk's context, in order, ignoring
+// whitespace. Lines scattered elsewhere in the file don't count. Hunks that
+// only delete are not checked, and a patch that adds nothing never matches.
+func (p *Patch) AdditionsPresent(content string) bool {
+	if p == nil {
+		return false
+	}
+	var lines []string
+	for _, line := range contentLines(content) {
+		lines = append(lines, stripSpace(line))
+	}
+	added := 0
+	for _, h := range p.Hunks {
+		var want []string
+		adds := false
+		for _, l := range h.Lines {
+			if l.Kind != Deleted {
+				want = append(want, stripSpace(l.Text))
+			}
+			adds = adds || l.Kind == Added
+		}
+		if !adds {
+			continue
+		}
+		if findNear(lines, want, 0, 0) < 0 {
+			return false
+		}
+		added++
+	}
+	return added > 0
+}
+
+// contentLines splits file content into lines without their newlines.
+func contentLines(content string) []string {
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// ApplyStatus is how well a patch applies to a file.
+type ApplyStatus string
+
+const (
+	ApplyClean     ApplyStatus = "clean"     // every hunk applies, possibly at an offset
+	ApplyFuzzed    ApplyStatus = "fuzzed"    // every hunk applies, some ignoring context
+	ApplyConflicts ApplyStatus = "conflicts" // some hunks don't apply
+)
+
+// Application is the outcome of applying a patch to a file.
+type Application struct {
+	Status ApplyStatus
+	Patch  *Patch // the hunks that apply, renumbered and trimmed to fit; nil if none do
+	Failed int    // number of hunks that don't apply
+}
+
+// Apply applies the patch to a file the way patch(1) does: a hunk whose
+// lines have moved is found at its new offset, searching outward from where
+// it should be, and a hunk may ignore up to fuzz lines of leading and
+// trailing context. exists says whether the file exists at all; a patch
+// creating a file only applies if it doesn't.
+func (p *Patch) Apply(content string, exists bool, fuzz int) *Application {
+	if p.Empty() {
+		return &Application{Status: ApplyClean}
+	}
+	if p.IsNew() == exists {
+		return &Application{Status: ApplyConflicts, Failed: max(len(p.Hunks), 1)}
+	}
+
+	var lines []string
+	if content != "" {
+		lines = contentLines(content)
+	}
+	applied := &Patch{OldPath: p.OldPath, NewPath: p.NewPath, OldMode: p.OldMode, NewMode: p.NewMode}
+	a := &Application{Status: ApplyClean}
+	offset, delta, next := 0, 0, 0
+	for _, h := range p.Hunks {
+		placed := false
+		for f := 0; f <= fuzz && !placed; f++ {
+			t, trimmed := trimContext(h, f)
+			if f > 0 && trimmed < f {
+				// Fuzzing further trims nothing more.
+				break
+			}
+			old := oldSide(t)
+			pos := findNear(lines, old, t.OldStart-1+offset, next)
+			if pos < 0 {
+				continue
+			}
+			placed = true
+			if trimmed > 0 {
+				a.Status = ApplyFuzzed
+			}
+
+			offset = pos - (t.OldStart - 1)
+			t.OldStart = startLine(pos, t.OldLines)
+			t.NewStart = startLine(pos+delta, t.NewLines)
+			delta += t.NewLines - t.OldLines
+			next = pos + len(old)
+			applied.Hunks = append(applied.Hunks, t)
+		}
+		if !placed {
+			a.Failed++
+		}
+	}
+
+	if a.Failed > 0 {
+		a.Status = ApplyConflicts
+	}
+	// A rename or mode change has no hunks to fail.
+	if len(applied.Hunks) > 0 || len(p.Hunks) == 0 {
+		a.Patch = applied
+	}
+	return a
+}
+
+// trimContext drops up to n context lines from each end of a hunk and
+// returns the result with how many lines came off the larger end.
+func trimContext(h Hunk, n int) (Hunk, int) {
+	lead := 0
+	for lead < n && lead < len(h.Lines) && h.Lines[lead].Kind == Context {
+		lead++
+	}
+	trail := 0
+	for trail < n && trail < len(h.Lines)-lead && h.Lines[len(h.Lines)-1-trail].Kind == Context {
+		trail++
+	}
+	t := h
+	t.Lines = h.Lines[lead : len(h.Lines)-trail]
+	t.OldStart += lead
+	t.NewStart += lead
+	t.OldLines -= lead + trail
+	t.NewLines -= lead + trail
+	return t, max(lead, trail)
+}
+
+// oldSide returns the lines a hunk expects to find: context and deletions.
+func oldSide(h Hunk) []string {
+	var old []string
+	for _, l := range h.Lines {
+		if l.Kind != Added {
+			old = append(old, l.Text)
+		}
+	}
+	return old
+}
+
+// findNear returns the index in lines at or after min where want occurs,
+// nearest to the expected index, or -1.
+func findNear(lines, want []string, expected, min int) int {
+	last := len(lines) - len(want)
+	expected = max(min, expected)
+	for d := 0; expected-d >= min || expected+d <= last; d++ {
+		if i := expected + d; i <= last && matchAt(lines, want, i) {
+			return i
+		}
+		if i := expected - d; d > 0 && i >= min && i <= last && matchAt(lines, want, i) {
+			return i
+		}
+	}
+	return -1
+}
+
+func matchAt(lines, want []string, i int) bool {
+	for j, w := range want {
+		if lines[i+j] != w {
+			return false
+		}
+	}
+	return true
+}
+
+// startLine is the hunk header start for a range of count lines after the
+// first pos: the first line, or the line before an empty range.
+func startLine(pos, count int) int {
+	if count == 0 {
+		return pos
+	}
+	return pos + 1
+}
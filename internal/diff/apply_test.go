@@ -0,0 +1,67 @@
+package diff
+
+import "testing"
+
+func TestApply(t *testing.T) {
+	base := numbered(60, nil)
+	patch := Compute("f", base, numbered(60, map[int]string{20: "twenty"}), 3)
+	created := &Patch{NewPath: "f", Hunks: Compute("f", "", "new\n", 3).Hunks}
+
+	tests := []struct {
+		name    string
+		patch   *Patch
+		content string
+		exists  bool
+		fuzz    int
+		status  ApplyStatus
+		start   int // OldStart of the first applied hunk; 0 to skip
+	}{
+		{"clean", patch, base, true, 0, ApplyClean, 18},
+		{"offset", patch, insertAt(insertAt(base, 0, "a"), 0, "b"), true, 0, ApplyClean, 20},
+		{"context changed, no fuzz", patch, numbered(60, map[int]string{17: "seventeen"}), true, 0, ApplyConflicts, 0},
+		{"context changed, fuzzed", patch, numbered(60, map[int]string{17: "seventeen"}), true, 2, ApplyFuzzed, 19},
+		{"changed line conflicts", patch, numbered(60, map[int]string{20: "other"}), true, 2, ApplyConflicts, 0},
+		{"new file", created, "", false, 0, ApplyClean, 0},
+		{"new file exists", created, "new\n", true, 0, ApplyConflicts, 0},
+		{"modify missing file", patch, "", false, 0, ApplyConflicts, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := tt.patch.Apply(tt.content, tt.exists, tt.fuzz)
+			if a.Status != tt.status {
+				t.Fatalf("Status = %s, want %s", a.Status, tt.status)
+			}
+			if tt.status == ApplyConflicts {
+				if a.Failed == 0 {
+					t.Error("conflicting patch reports no failed hunks")
+				}
+				return
+			}
+			if a.Patch == nil {
+				t.Fatal("applied patch is nil")
+			}
+			if tt.start != 0 && a.Patch.Hunks[0].OldStart != tt.start {
+				t.Errorf("hunk applied at %d, want %d", a.Patch.Hunks[0].OldStart, tt.start)
+			}
+		})
+	}
+}
+
+func TestAdditionsPresent(t *testing.T) {
+	patch := Compute("f", numbered(60, nil), numbered(60, map[int]string{20: "twenty"}), 3)
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{"applied", numbered(60, map[int]string{20: "twenty"}), true},
+		{"applied with other whitespace", numbered(60, map[int]string{20: "  twenty"}), true},
+		{"not applied", numbered(60, nil), false},
+		{"line elsewhere", numbered(60, map[int]string{50: "twenty"}), false},
+	}
+	for _, tt := range tests {
+		if got := patch.AdditionsPresent(tt.content); got != tt.want {
+			t.Errorf("%s: AdditionsPresent = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
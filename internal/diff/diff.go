@@ -0,0 +1,317 @@
+// Package diff models unified diffs: it parses the patches GitHub's API
+// returns, renders them back out, and computes them from file contents.
+package diff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Kind is the role of a line in a hunk, written as its diff marker.
+type Kind byte
+
+const (
+	Context Kind = ' '
+	Added   Kind = '+'
+	Deleted Kind = '-'
+)
+
+// Line is one line of a hunk.
+type Line struct {
+	Kind      Kind
+	Text      string // without the marker or trailing newline
+	Old       int    // line number in the old file; 0 for added lines
+	New       int    // line number in the new file; 0 for deleted lines
+	NoNewline bool   // the line ends its file without a trailing newline
+}
+
+// Hunk is a run of changed lines with surrounding context.
+type Hunk struct {
+	OldStart, OldLines int
+	NewStart, NewLines int
+	Section            string // text after the closing @@, usually a function signature
+	Lines              []Line
+}
+
+// defaultMode is the mode of a regular, non-executable file, assumed when
+// a patch that creates or deletes a file doesn't say.
+const defaultMode = "100644"
+
+// Patch is the diff of a single file. OldPath is empty for a file the patch
+// creates and NewPath for one it deletes; they differ for a rename.
+type Patch struct {
+	OldPath, NewPath string
+	OldMode, NewMode string // e.g. "100755"; empty when unknown
+	Hunks            []Hunk
+}
+
+// Parse parses the diff of a single file. It accepts GitHub's patch format,
+// which has only hunks, as well as git diffs with file headers. path names
+// the file when the text doesn't.
+func Parse(path, text string) (*Patch, error) {
+	p := &Patch{OldPath: path, NewPath: path}
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+
+	i := 0
+	for ; i < len(lines) && !strings.HasPrefix(lines[i], "@@"); i++ {
+		line := lines[i]
+		name, value, _ := strings.Cut(line, " ")
+		switch {
+		case strings.HasPrefix(line, "--- "):
+			p.OldPath = headerPath(line[4:])
+		case strings.HasPrefix(line, "+++ "):
+			p.NewPath = headerPath(line[4:])
+		case strings.HasPrefix(line, "new file mode "):
+			p.OldPath, p.NewMode = "", line[len("new file mode "):]
+		case strings.HasPrefix(line, "deleted file mode "):
+			p.NewPath, p.OldMode = "", line[len("deleted file mode "):]
+		case strings.HasPrefix(line, "rename from "), strings.HasPrefix(line, "copy from "):
+			_, p.OldPath, _ = strings.Cut(value, " ")
+		case strings.HasPrefix(line, "rename to "), strings.HasPrefix(line, "copy to "):
+			_, p.NewPath, _ = strings.Cut(value, " ")
+		case name == "old" && strings.HasPrefix(value, "mode "):
+			p.OldMode = value[len("mode "):]
+		case name == "new" && strings.HasPrefix(value, "mode "):
+			p.NewMode = value[len("mode "):]
+		case name == "diff", name == "index", name == "similarity", name == "dissimilarity":
+		case line == "" && i == len(lines)-1:
+			// Empty text.
+		default:
+			return nil, fmt.Errorf("unexpected line %d before first hunk: %q", i+1, line)
+		}
+	}
+
+	for i < len(lines) {
+		h, err := parseHunkHeader(lines[i])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		i++
+
+		old, new := h.OldStart, h.NewStart
+		oldLeft, newLeft := h.OldLines, h.NewLines
+		for oldLeft > 0 || newLeft > 0 {
+			if i >= len(lines) {
+				return nil, fmt.Errorf("hunk at line %d is truncated", i)
+			}
+			line := lines[i]
+			if line == "" {
+				// Some tools strip the space marking an empty context line.
+				line = " "
+			}
+			l := Line{Kind: Kind(line[0]), Text: line[1:]}
+			switch l.Kind {
+			case Context:
+				l.Old, l.New = old, new
+				old++
+				new++
+				oldLeft--
+				newLeft--
+			case Deleted:
+				l.Old = old
+				old++
+				oldLeft--
+			case Added:
+				l.New = new
+				new++
+				newLeft--
+			default:
+				return nil, fmt.Errorf("line %d: unexpected %q in hunk", i+1, line)
+			}
+			if oldLeft < 0 || newLeft < 0 {
+				return nil, fmt.Errorf("line %d: hunk is longer than its header says", i+1)
+			}
+			h.Lines = append(h.Lines, l)
+			i++
+			if i < len(lines) && strings.HasPrefix(lines[i], `\`) {
+				h.Lines[len(h.Lines)-1].NoNewline = true
+				i++
+			}
+		}
+		p.Hunks = append(p.Hunks, h)
+	}
+	return p, nil
+}
+
+// parseHunkHeader parses "@@ -l,s +l,s @@ section".
+func parseHunkHeader(line string) (Hunk, error) {
+	var h Hunk
+	rest, ok := strings.CutPrefix(line, "@@ -")
+	if !ok {
+		return h, fmt.Errorf("expected hunk header, got %q", line)
+	}
+	ranges, section, ok := strings.Cut(rest, " @@")
+	if !ok {
+		return h, fmt.Errorf("malformed hunk header %q", line)
+	}
+	oldRange, newRange, ok := strings.Cut(ranges, " +")
+	if !ok {
+		return h, fmt.Errorf("malformed hunk header %q", line)
+	}
+	var err error
+	if h.OldStart, h.OldLines, err = parseRange(oldRange); err != nil {
+		return h, fmt.Errorf("malformed hunk header %q", line)
+	}
+	if h.NewStart, h.NewLines, err = parseRange(newRange); err != nil {
+		return h, fmt.Errorf("malformed hunk header %q", line)
+	}
+	h.Section = strings.TrimPrefix(section, " ")
+	return h, nil
+}
+
+func parseRange(s string) (start, count int, err error) {
+	startText, countText, hasCount := strings.Cut(s, ",")
+	if start, err = strconv.Atoi(startText); err != nil {
+		return 0, 0, err
+	}
+	count = 1
+	if hasCount {
+		if count, err = strconv.Atoi(countText); err != nil {
+			return 0, 0, err
+		}
+	}
+	return start, count, nil
+}
+
+// headerPath strips the a/ or b/ prefix from a ---/+++ header path. It
+// returns "" for /dev/null.
+func headerPath(s string) string {
+	s, _, _ = strings.Cut(s, "\t")
+	if s == "/dev/null" {
+		return ""
+	}
+	if len(s) > 2 && (s[:2] == "a/" || s[:2] == "b/") {
+		return s[2:]
+	}
+	return s
+}
+
+// Empty reports whether the patch changes nothing: no lines, and no
+// creation, deletion, rename or mode change.
+func (p *Patch) Empty() bool {
+	return p == nil || len(p.Hunks) == 0 && !p.IsNew() && !p.IsDeleted() && !p.IsRename() && !p.modeChanged()
+}
+
+// IsNew reports whether the patch creates its file.
+func (p *Patch) IsNew() bool {
+	return p.OldPath == "" && p.NewPath != ""
+}
+
+// IsDeleted reports whether the patch deletes its file.
+func (p *Patch) IsDeleted() bool {
+	return p.NewPath == "" && p.OldPath != ""
+}
+
+// IsRename reports whether the patch moves its file.
+func (p *Patch) IsRename() bool {
+	return p.OldPath != "" && p.NewPath != "" && p.OldPath != p.NewPath
+}
+
+func (p *Patch) modeChanged() bool {
+	return p.OldMode != "" && p.NewMode != "" && p.OldMode != p.NewMode
+}
+
+// Stats returns the number of added and deleted lines.
+func (p *Patch) Stats() (additions, deletions int) {
+	if p == nil {
+		return 0, 0
+	}
+	for _, h := range p.Hunks {
+		for _, l := range h.Lines {
+			switch l.Kind {
+			case Added:
+				additions++
+			case Deleted:
+				deletions++
+			}
+		}
+	}
+	return additions, deletions
+}
+
+// Body renders the hunks in GitHub's patch format: no file headers and no
+// trailing newline. Patches with identical changes have identical bodies.
+func (p *Patch) Body() string {
+	if p.Empty() {
+		return ""
+	}
+	var b strings.Builder
+	for _, h := range p.Hunks {
+		h.write(&b)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// String renders the patch as a git diff, with the headers git apply needs
+// to create, delete, rename or chmod the file.
+func (p *Patch) String() string {
+	if p.Empty() {
+		return ""
+	}
+	oldPath, newPath := p.OldPath, p.NewPath
+	if oldPath == "" {
+		oldPath = newPath
+	}
+	if newPath == "" {
+		newPath = oldPath
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "diff --git a/%s b/%s\n", oldPath, newPath)
+	switch {
+	case p.IsNew():
+		fmt.Fprintf(&b, "new file mode %s\n", modeOrDefault(p.NewMode))
+	case p.IsDeleted():
+		fmt.Fprintf(&b, "deleted file mode %s\n", modeOrDefault(p.OldMode))
+	case p.modeChanged():
+		fmt.Fprintf(&b, "old mode %s\nnew mode %s\n", p.OldMode, p.NewMode)
+	}
+	if p.IsRename() {
+		fmt.Fprintf(&b, "rename from %s\nrename to %s\n", p.OldPath, p.NewPath)
+	}
+	if len(p.Hunks) > 0 {
+		fmt.Fprintf(&b, "--- %s\n+++ %s\n", headerName("a/", p.OldPath), headerName("b/", p.NewPath))
+	}
+	for _, h := range p.Hunks {
+		h.write(&b)
+	}
+	return b.String()
+}
+
+func modeOrDefault(mode string) string {
+	if mode == "" {
+		return defaultMode
+	}
+	return mode
+}
+
+func headerName(prefix, path string) string {
+	if path == "" {
+		return "/dev/null"
+	}
+	return prefix + path
+}
+
+func (h Hunk) write(b *strings.Builder) {
+	fmt.Fprintf(b, "@@ -%s +%s @@", hunkRange(h.OldStart, h.OldLines), hunkRange(h.NewStart, h.NewLines))
+	if h.Section != "" {
+		b.WriteString(" " + h.Section)
+	}
+	b.WriteByte('\n')
+	for _, l := range h.Lines {
+		b.WriteByte(byte(l.Kind))
+		b.WriteString(l.Text)
+		b.WriteByte('\n')
+		if l.NoNewline {
+			b.WriteString("\\ No newline at end of file\n")
+		}
+	}
+}
+
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return strconv.Itoa(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
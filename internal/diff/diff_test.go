@@ -0,0 +1,62 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func numbered(n int, edits map[int]string) string {
+	var lines []string
+	for i := 0; i < n; i++ {
+		line := fmt.Sprintf("line %d", i)
+		if s, ok := edits[i]; ok {
+			line = s
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// insertAt inserts line before the zero-based line i of text.
+func insertAt(text string, i int, line string) string {
+	lines := strings.SplitAfter(text, "\n")
+	return strings.Join(lines[:i], "") + line + "\n" + strings.Join(lines[i:], "")
+}
+
+func TestParseRoundTrip(t *testing.T) {
+	multi := Compute("f.go", numbered(60, nil), numbered(60, map[int]string{5: "five", 30: "thirty", 55: "fifty-five"}), 3)
+	if len(multi.Hunks) < 2 {
+		t.Fatalf("want several hunks, got %d", len(multi.Hunks))
+	}
+	created := Compute("f.go", "", "a\nb\n", 3)
+	created.OldPath, created.NewMode = "", "100644"
+	deleted := Compute("f.go", "a\nb\n", "", 3)
+	deleted.NewPath, deleted.OldMode = "", "100644"
+	renamed := Compute("f.go", numbered(10, nil), numbered(10, map[int]string{4: "four"}), 3)
+	renamed.NewPath = "g.go"
+	chmod := &Patch{OldPath: "f.go", NewPath: "f.go", OldMode: "100644", NewMode: "100755"}
+
+	for name, p := range map[string]*Patch{"multi": multi, "created": created, "deleted": deleted, "renamed": renamed, "chmod": chmod} {
+		t.Run(name, func(t *testing.T) {
+			got, err := Parse("f.go", p.String())
+			if err != nil {
+				t.Fatalf("Parse(String()): %v", err)
+			}
+			if !reflect.DeepEqual(got, p) {
+				t.Errorf("Parse(String()) = %+v, want %+v", got, p)
+			}
+			if len(p.Hunks) == 0 {
+				return
+			}
+			got, err = Parse("f.go", p.Body())
+			if err != nil {
+				t.Fatalf("Parse(Body()): %v", err)
+			}
+			if !reflect.DeepEqual(got.Hunks, p.Hunks) {
+				t.Errorf("Parse(Body()) hunks = %+v, want %+v", got.Hunks, p.Hunks)
+			}
+		})
+	}
+}
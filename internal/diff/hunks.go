@@ -0,0 +1,243 @@
+package diff
+
+import (
+	"sort"
+	"strings"
+)
+
+// Split breaks a hunk into one hunk per run of changed lines, each keeping
+// up to context lines of the surrounding context. A hunk merges changes
+// that happen to sit close together; split, they can be compared one by one.
+func (h Hunk) Split(context int) []Hunk {
+	var parts []Hunk
+	for i := 0; i < len(h.Lines); {
+		if h.Lines[i].Kind == Context {
+			i++
+			continue
+		}
+		end := i
+		for end < len(h.Lines) && h.Lines[end].Kind != Context {
+			end++
+		}
+		lo, hi := i, end
+		for lo > 0 && i-lo < context && h.Lines[lo-1].Kind == Context {
+			lo--
+		}
+		for hi < len(h.Lines) && hi-end < context && h.Lines[hi].Kind == Context {
+			hi++
+		}
+		parts = append(parts, subHunk(h, lo, hi))
+		i = end
+	}
+	return parts
+}
+
+// subHunk returns the lines lo..hi of a hunk as a hunk of their own.
+func subHunk(h Hunk, lo, hi int) Hunk {
+	part := Hunk{Lines: h.Lines[lo:hi]}
+	old, new := h.OldStart, h.NewStart
+	if h.OldLines == 0 {
+		old++
+	}
+	if h.NewLines == 0 {
+		new++
+	}
+	for _, l := range h.Lines[:lo] {
+		if l.Kind != Added {
+			old++
+		}
+		if l.Kind != Deleted {
+			new++
+		}
+	}
+	for _, l := range part.Lines {
+		if l.Kind != Added {
+			part.OldLines++
+		}
+		if l.Kind != Deleted {
+			part.NewLines++
+		}
+	}
+	part.OldStart = startLine(old-1, part.OldLines)
+	part.NewStart = startLine(new-1, part.NewLines)
+	return part
+}
+
+// ChangeKey identifies what a hunk changes: each run of deleted and added
+// lines, with the nearest non-blank context line on either side, ignoring
+// whitespace and position. The context ties a change to the code around it,
+// so the same line added in unrelated places is not the same change.
+func (h Hunk) ChangeKey() string {
+	var b strings.Builder
+	for i := 0; i < len(h.Lines); {
+		if h.Lines[i].Kind == Context {
+			i++
+			continue
+		}
+		end := i
+		for end < len(h.Lines) && h.Lines[end].Kind != Context {
+			end++
+		}
+		b.WriteString("@" + nearestContext(h.Lines, i-1, -1) + "\n")
+		for _, l := range h.Lines[i:end] {
+			b.WriteByte(byte(l.Kind))
+			b.WriteString(stripSpace(l.Text))
+			b.WriteByte('\n')
+		}
+		b.WriteString("@" + nearestContext(h.Lines, end, 1) + "\n")
+		i = end
+	}
+	return b.String()
+}
+
+// nearestContext returns the first non-blank context line from lines[i]
+// on in direction step, whitespace stripped, stopping at a changed line.
+func nearestContext(lines []Line, i, step int) string {
+	for ; i >= 0 && i < len(lines) && lines[i].Kind == Context; i += step {
+		if text := stripSpace(lines[i].Text); text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+// ChangedRange returns the old-file lines [start, end) the hunk's changes
+// touch, leaving out its context. An insertion touches the line it goes
+// before; lines added in place of deleted ones touch only those.
+func (h Hunk) ChangedRange() (start, end int) {
+	pos := h.firstOld()
+	start, end = -1, pos
+	deleted := false
+	for _, l := range h.Lines {
+		switch l.Kind {
+		case Context:
+			deleted = false
+		case Deleted:
+			deleted = true
+			end = pos + 1
+		case Added:
+			if !deleted {
+				end = max(end, pos+1)
+			}
+		}
+		if l.Kind != Context && start < 0 {
+			start = pos
+		}
+		if l.Kind != Added {
+			pos++
+		}
+	}
+	if start < 0 {
+		return end, end
+	}
+	return start, end
+}
+
+// firstOld returns the old-file line number of the hunk's first line.
+func (h Hunk) firstOld() int {
+	if h.OldLines == 0 {
+		return h.OldStart + 1
+	}
+	return h.OldStart
+}
+
+// String renders the hunk with its header.
+func (h Hunk) String() string {
+	var b strings.Builder
+	h.write(&b)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// WithHunks returns a patch to the same file made of the given hunks,
+// ordered by position, with their new-file line numbers recomputed. Hunks
+// whose context overlaps or touches are merged into one. Their changes must
+// not overlap.
+func (p *Patch) WithHunks(hunks []Hunk) *Patch {
+	sorted := append([]Hunk(nil), hunks...)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, _ := sorted[i].ChangedRange()
+		b, _ := sorted[j].ChangedRange()
+		return a < b
+	})
+
+	q := &Patch{OldPath: p.OldPath, NewPath: p.NewPath, OldMode: p.OldMode, NewMode: p.NewMode}
+	for _, h := range sorted {
+		if n := len(q.Hunks); n > 0 && h.firstOld() <= q.Hunks[n-1].firstOld()+q.Hunks[n-1].OldLines {
+			q.Hunks[n-1] = merge(q.Hunks[n-1], h)
+			continue
+		}
+		q.Hunks = append(q.Hunks, h)
+	}
+
+	delta := 0
+	for i := range q.Hunks {
+		h := &q.Hunks[i]
+		h.NewStart = startLine(h.firstOld()-1+delta, h.NewLines)
+		delta += h.NewLines - h.OldLines
+		h.renumber()
+	}
+	return q
+}
+
+// merge joins hunk b onto a, whose lines overlap or touch b's and whose
+// changes all come before b's. Context both have is kept once, and a's
+// trailing context stops where b's changes start.
+func merge(a, b Hunk) Hunk {
+	cut, _ := b.ChangedRange()
+	m := Hunk{Section: a.Section}
+	pos := a.firstOld()
+	for _, l := range a.Lines {
+		if l.Kind == Context && pos >= cut {
+			break
+		}
+		m.Lines = append(m.Lines, l)
+		if l.Kind != Added {
+			pos++
+		}
+	}
+	end := pos
+	pos = b.firstOld()
+	for _, l := range b.Lines {
+		if l.Kind == Context && pos < end {
+			pos++
+			continue
+		}
+		m.Lines = append(m.Lines, l)
+		if l.Kind != Added {
+			pos++
+		}
+	}
+	for _, l := range m.Lines {
+		if l.Kind != Added {
+			m.OldLines++
+		}
+		if l.Kind != Deleted {
+			m.NewLines++
+		}
+	}
+	m.OldStart = startLine(a.firstOld()-1, m.OldLines)
+	return m
+}
+
+// renumber sets each line's old and new line numbers from the hunk's
+// header. The lines are copied first, since hunks share them with the
+// patches they were split from.
+func (h *Hunk) renumber() {
+	h.Lines = append([]Line(nil), h.Lines...)
+	old, new := h.firstOld(), h.NewStart
+	if h.NewLines == 0 {
+		new++
+	}
+	for i := range h.Lines {
+		l := &h.Lines[i]
+		l.Old, l.New = 0, 0
+		if l.Kind != Added {
+			l.Old = old
+			old++
+		}
+		if l.Kind != Deleted {
+			l.New = new
+			new++
+		}
+	}
+}
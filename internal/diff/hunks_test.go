@@ -0,0 +1,117 @@
+package diff
+
+import "testing"
+
+func TestSplit(t *testing.T) {
+	h := mustParse(t, "@@ -10,9 +10,9 @@\n a\n b\n c\n-d\n+D\n e\n f\n g\n-h\n+H\n i").Hunks[0]
+	insert := mustParse(t, "@@ -10,2 +10,3 @@\n a\n+x\n b").Hunks[0]
+
+	tests := []struct {
+		name    string
+		hunk    Hunk
+		context int
+		want    [][4]int // OldStart, OldLines, NewStart, NewLines of each part
+	}{
+		{"one line of context", h, 1, [][4]int{{12, 3, 12, 3}, {16, 3, 16, 3}}},
+		{"no context", h, 0, [][4]int{{13, 1, 13, 1}, {17, 1, 17, 1}}},
+		{"context runs into the other change", h, 3, [][4]int{{10, 7, 10, 7}, {14, 5, 14, 5}}},
+		{"insertion without context", insert, 0, [][4]int{{10, 0, 11, 1}}},
+		{"insertion with context", insert, 1, [][4]int{{10, 2, 10, 3}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts := tt.hunk.Split(tt.context)
+			if len(parts) != len(tt.want) {
+				t.Fatalf("got %d parts, want %d", len(parts), len(tt.want))
+			}
+			for i, p := range parts {
+				got := [4]int{p.OldStart, p.OldLines, p.NewStart, p.NewLines}
+				if got != tt.want[i] {
+					t.Errorf("part %d = %v, want %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestChangeKey(t *testing.T) {
+	a := Compute("f", numbered(60, nil), insertAt(numbered(60, nil), 10, "}"), 3)
+	b := Compute("f", numbered(60, nil), insertAt(numbered(60, nil), 40, "}"), 3)
+	shifted := Compute("f", "extra\n"+numbered(60, nil), "extra\n"+insertAt(numbered(60, nil), 10, "  }"), 5)
+	if a.Hunks[0].ChangeKey() == b.Hunks[0].ChangeKey() {
+		t.Error("the same line added in different places has the same key")
+	}
+	if a.Hunks[0].ChangeKey() != shifted.Hunks[0].ChangeKey() {
+		t.Error("the same change at another offset, with more context and other whitespace, has a different key")
+	}
+}
+
+func TestWithHunks(t *testing.T) {
+	tests := []struct {
+		name  string
+		edits map[int]string
+	}{
+		{"far apart", map[int]string{5: "five", 40: "forty"}},
+		{"sharing context", map[int]string{5: "five", 9: "nine"}},
+		{"touching", map[int]string{5: "five", 12: "twelve"}},
+		{"adjacent lines", map[int]string{5: "five", 6: "six"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old, new := numbered(60, nil), numbered(60, tt.edits)
+			want := Compute("f", old, new, 3)
+			var parts []Hunk
+			for _, h := range want.Hunks {
+				parts = append(parts, h.Split(3)...)
+			}
+			// Reversed, to check they are put back in order.
+			for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+				parts[i], parts[j] = parts[j], parts[i]
+			}
+			got := want.WithHunks(parts)
+			if got.String() != want.String() {
+				t.Errorf("WithHunks =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+
+	t.Run("renumbers after an insertion", func(t *testing.T) {
+		full := Compute("f", numbered(60, nil), insertAt(numbered(60, map[int]string{40: "forty"}), 10, "new"), 3)
+		parts := full.Hunks[0].Split(3)
+		parts = append(parts, full.Hunks[1].Split(3)...)
+		got := full.WithHunks(parts[1:])
+		if h := got.Hunks[0]; h.OldStart != 38 || h.NewStart != 38 {
+			t.Errorf("without the insertion, hunk starts at -%d +%d, want -38 +38", h.OldStart, h.NewStart)
+		}
+		if _, err := Parse("f", got.String()); err != nil {
+			t.Errorf("result doesn't parse: %v", err)
+		}
+	})
+}
+
+func TestChangedRange(t *testing.T) {
+	tests := []struct {
+		text       string
+		start, end int
+	}{
+		{"@@ -10,7 +10,7 @@\n a\n b\n c\n-d\n+D\n e\n f\n g", 13, 14},
+		{"@@ -10,2 +10,3 @@\n a\n+x\n b", 11, 12},
+		{"@@ -0,0 +1,2 @@\n+a\n+b", 1, 2},
+		{"@@ -5,4 +5,2 @@\n a\n-b\n-c\n d", 6, 8},
+	}
+	for _, tt := range tests {
+		start, end := mustParse(t, tt.text).Hunks[0].ChangedRange()
+		if start != tt.start || end != tt.end {
+			t.Errorf("ChangedRange(%q) = %d, %d, want %d, %d", tt.text, start, end, tt.start, tt.end)
+		}
+	}
+}
+
+func mustParse(t *testing.T, text string) *Patch {
+	t.Helper()
+	p, err := Parse("f", text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
@@ -0,0 +1,218 @@
+package diff
+
+import "strings"
+
+// maxEditDistance bounds the work Myers' algorithm does. Files differing by
+// more lines than this are diffed as a whole-region replacement instead.
+const maxEditDistance = 4000
+
+// op is one step of an edit script: a line kept, deleted or inserted.
+type op struct {
+	kind byte // ' ', '-' or '+'
+	a, b int  // line index in old (for ' ' and '-') and new (for ' ' and '+')
+}
+
+// Compute diffs two versions of a file line by line, with the given number
+// of context lines around each change. The patch has no hunks if the
+// contents are equal.
+func Compute(path, old, new string, context int) *Patch {
+	a, b := splitLines(old), splitLines(new)
+	return &Patch{OldPath: path, NewPath: path, Hunks: buildHunks(editScript(a, b), a, b, context)}
+}
+
+// splitLines splits text into lines, each keeping its trailing newline so
+// that a missing newline at end of file counts as a difference.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// editScript returns the shortest edit script turning a into b. Common
+// prefix and suffix are matched up front, which is cheap and keeps the
+// Myers search small for typical localized changes.
+func editScript(a, b []string) []op {
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+
+	var ops []op
+	for i := 0; i < pre; i++ {
+		ops = append(ops, op{' ', i, i})
+	}
+	for _, o := range myers(a[pre:len(a)-suf], b[pre:len(b)-suf]) {
+		ops = append(ops, op{o.kind, o.a + pre, o.b + pre})
+	}
+	for i := 0; i < suf; i++ {
+		ops = append(ops, op{' ', len(a) - suf + i, len(b) - suf + i})
+	}
+	return ops
+}
+
+// myers implements Eugene Myers' O(ND) difference algorithm, keeping each
+// round's frontier so the edit path can be recovered by backtracking.
+func myers(a, b []string) []op {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil
+	}
+
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	// trace[d] is the frontier before round d, for diagonals -(d-1)..d-1.
+	var trace [][]int
+
+	for d := 0; d <= max; d++ {
+		if d > maxEditDistance {
+			return replaceAll(n, m)
+		}
+		if d == 0 {
+			trace = append(trace, nil)
+		} else {
+			trace = append(trace, append([]int(nil), v[offset-d+1:offset+d]...))
+		}
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, n, m)
+			}
+		}
+	}
+	return replaceAll(n, m)
+}
+
+func backtrack(trace [][]int, n, m int) []op {
+	var ops []op
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		frontier := trace[d]
+		at := func(k int) int { return frontier[k+d-1] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, op{' ', x, y})
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, op{'+', x, y})
+		} else {
+			x--
+			ops = append(ops, op{'-', x, y})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		ops = append(ops, op{' ', x, y})
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// replaceAll is the edit script deleting every old line and inserting every
+// new one.
+func replaceAll(n, m int) []op {
+	var ops []op
+	for i := 0; i < n; i++ {
+		ops = append(ops, op{'-', i, 0})
+	}
+	for j := 0; j < m; j++ {
+		ops = append(ops, op{'+', n, j})
+	}
+	return ops
+}
+
+// buildHunks groups an edit script into hunks with the given number of
+// context lines. Hunks separated by no more than twice the context are
+// merged, as diff and git do.
+func buildHunks(ops []op, a, b []string, context int) []Hunk {
+	type span struct{ lo, hi int }
+	var spans []span
+	for i, o := range ops {
+		if o.kind == ' ' {
+			continue
+		}
+		lo, hi := i-context, i+context+1
+		if lo < 0 {
+			lo = 0
+		}
+		if hi > len(ops) {
+			hi = len(ops)
+		}
+		if n := len(spans); n > 0 && lo <= spans[n-1].hi {
+			spans[n-1].hi = hi
+			continue
+		}
+		spans = append(spans, span{lo, hi})
+	}
+
+	var hunks []Hunk
+	for _, s := range spans {
+		var h Hunk
+		for _, o := range ops[s.lo:s.hi] {
+			var l Line
+			switch o.kind {
+			case ' ':
+				l = Line{Kind: Context, Text: a[o.a], Old: o.a + 1, New: o.b + 1}
+				h.OldLines++
+				h.NewLines++
+			case '-':
+				l = Line{Kind: Deleted, Text: a[o.a], Old: o.a + 1}
+				h.OldLines++
+			case '+':
+				l = Line{Kind: Added, Text: b[o.b], New: o.b + 1}
+				h.NewLines++
+			}
+			l.NoNewline = !strings.HasSuffix(l.Text, "\n")
+			l.Text = strings.TrimSuffix(l.Text, "\n")
+			h.Lines = append(h.Lines, l)
+		}
+
+		// An empty range starts at the line before it, as in diff -u.
+		h.OldStart, h.NewStart = ops[s.lo].a, ops[s.lo].b
+		if h.OldLines > 0 {
+			h.OldStart++
+		}
+		if h.NewLines > 0 {
+			h.NewStart++
+		}
+		hunks = append(hunks, h)
+	}
+	return hunks
+}
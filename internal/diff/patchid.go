@@ -0,0 +1,76 @@
+package diff
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+	"unicode"
+)
+
+// PatchID identifies the change a set of file patches makes, like
+// git patch-id --stable: whitespace and line numbers are ignored, as is the
+// order of the files. A commit and its cherry-picks share a patch-id as
+// long as the surrounding context is the same.
+func PatchID(patches []*Patch) string {
+	var sum [sha1.Size]byte
+	for _, p := range patches {
+		h := sha1.New()
+		for _, line := range strings.Split(p.String(), "\n") {
+			if strings.HasPrefix(line, "@@") {
+				continue
+			}
+			h.Write([]byte(stripSpace(line)))
+		}
+
+		// Per-file hashes are added, not chained, so file order doesn't
+		// matter; this is the same carry-add git uses.
+		var carry int
+		for i, b := range h.Sum(nil) {
+			carry += int(sum[i]) + int(b)
+			sum[i] = byte(carry)
+			carry >>= 8
+		}
+	}
+	return hex.EncodeToString(sum[:])
+}
+
+func stripSpace(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// Fingerprint identifies the change a patch makes for grouping: its runs of
+// deleted and added lines, in order, each with the nearest non-blank
+// context line on either side, plus any creation, deletion, rename or mode
+// change. Unlike Body it ignores hunk positions, how much context surrounds
+// each change, whitespace and line endings, so forks based on different
+// upstream commits making the same change share a fingerprint.
+func (p *Patch) Fingerprint() string {
+	var b strings.Builder
+	switch {
+	case p.IsNew():
+		b.WriteString("new file\n")
+	case p.IsDeleted():
+		b.WriteString("deleted file\n")
+	case p.IsRename():
+		b.WriteString("rename to " + p.NewPath + "\n")
+	}
+	if p.modeChanged() {
+		b.WriteString("mode " + p.NewMode + "\n")
+	}
+	for _, h := range p.Hunks {
+		// Split with all the hunk's context, so each run keeps its
+		// nearest non-blank context line.
+		for _, change := range h.Split(len(h.Lines)) {
+			// A blank line separates runs; every line of a key starts
+			// with a marker, so none is blank.
+			b.WriteString("\n")
+			b.WriteString(change.ChangeKey())
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,57 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	base := numbered(60, nil)
+	change := Compute("f", base, numbered(60, map[int]string{20: "twenty"}), 3)
+	crlf := strings.ReplaceAll(base, "\n", "\r\n")
+
+	tests := []struct {
+		name  string
+		other *Patch
+		same  bool
+	}{
+		{"offset", Compute("f", "a\nb\n"+base, "a\nb\n"+numbered(60, map[int]string{20: "twenty"}), 3), true},
+		{"more context", Compute("f", base, numbered(60, map[int]string{20: "twenty"}), 6), true},
+		{"whitespace", Compute("f", base, numbered(60, map[int]string{20: "\ttwenty "}), 3), true},
+		{"line endings", Compute("f", crlf, strings.ReplaceAll(numbered(60, map[int]string{20: "twenty"}), "\n", "\r\n"), 3), true},
+		{"other line", Compute("f", base, numbered(60, map[int]string{20: "twenty!"}), 3), false},
+		{"same line elsewhere", Compute("f", base, numbered(60, map[int]string{40: "twenty"}), 3), false},
+		{"new file", &Patch{NewPath: "f", Hunks: change.Hunks}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.other.Fingerprint() == change.Fingerprint(); got != tt.same {
+			t.Errorf("%s: same fingerprint = %v, want %v", tt.name, got, tt.same)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	base := numbered(60, nil)
+	one := Compute("f", base, numbered(60, map[int]string{5: "five"}), 3)
+	two := Compute("f", base, numbered(60, map[int]string{5: "five", 40: "forty"}), 3)
+	other := Compute("f", base, numbered(60, map[int]string{7: "seven"}), 3)
+
+	tests := []struct {
+		name  string
+		a, b  *Patch
+		extra int
+		ok    bool
+	}{
+		{"superset", two, one, 2, true},
+		{"subset", one, two, 0, false},
+		{"identical", one, one, 0, false},
+		{"disjoint", two, other, 0, false},
+		{"empty", two, &Patch{}, 0, false},
+	}
+	for _, tt := range tests {
+		extra, ok := Contains(tt.a, tt.b)
+		if extra != tt.extra || ok != tt.ok {
+			t.Errorf("%s: Contains = %d, %v, want %d, %v", tt.name, extra, ok, tt.extra, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package diff
+
+// Similarity scores how alike two patches' changes are, from 0 for nothing
+// in common to 1 for the same lines added and deleted. Whitespace, context
+// and line positions are ignored.
+func Similarity(a, b *Patch) float64 {
+	ca, cb := changedLines(a), changedLines(b)
+	var shared, total int
+	for line, n := range ca {
+		m := cb[line]
+		shared += min(n, m)
+		total += max(n, m)
+	}
+	for line, m := range cb {
+		if _, ok := ca[line]; !ok {
+			total += m
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(shared) / float64(total)
+}
+
+// changedLines counts the patch's added and deleted lines, keyed by marker
+// and whitespace-stripped text.
+func changedLines(p *Patch) map[string]int {
+	lines := make(map[string]int)
+	if p == nil {
+		return lines
+	}
+	for _, h := range p.Hunks {
+		for _, l := range h.Lines {
+			if l.Kind != Context {
+				lines[string(l.Kind)+stripSpace(l.Text)]++
+			}
+		}
+	}
+	return lines
+}
+
+// Core returns the changed lines every patch makes, in the order the first
+// patch makes them, and for each patch the changed lines it makes beyond
+// those. Lines are compared as in Similarity.
+func Core(patches []*Patch) (core []Line, extra [][]Line) {
+	if len(patches) == 0 {
+		return nil, nil
+	}
+	shared := changedLines(patches[0])
+	for _, p := range patches[1:] {
+		counts := changedLines(p)
+		for key, n := range shared {
+			shared[key] = min(n, counts[key])
+		}
+	}
+
+	take := func(p *Patch) (common, rest []Line) {
+		left := make(map[string]int, len(shared))
+		for key, n := range shared {
+			left[key] = n
+		}
+		for _, l := range p.changes() {
+			key := string(l.Kind) + stripSpace(l.Text)
+			if left[key] > 0 {
+				left[key]--
+				common = append(common, l)
+			} else {
+				rest = append(rest, l)
+			}
+		}
+		return common, rest
+	}
+	core, _ = take(patches[0])
+	for _, p := range patches {
+		_, rest := take(p)
+		extra = append(extra, rest)
+	}
+	return core, extra
+}
+
+// changes returns the patch's added and deleted lines in order.
+func (p *Patch) changes() []Line {
+	if p == nil {
+		return nil
+	}
+	var lines []Line
+	for _, h := range p.Hunks {
+		for _, l := range h.Lines {
+			if l.Kind != Context {
+				lines = append(lines, l)
+			}
+		}
+	}
+	return lines
+}
+
+// Contains reports whether patch a makes every change b does and more, and
+// how many changed lines it makes beyond b's. Lines are compared as in
+// Similarity.
+func Contains(a, b *Patch) (extra int, ok bool) {
+	ca, cb := changedLines(a), changedLines(b)
+	if len(cb) == 0 {
+		return 0, false
+	}
+	for line, n := range cb {
+		if ca[line] < n {
+			return 0, false
+		}
+	}
+	for line, n := range ca {
+		extra += n - cb[line]
+	}
+	return extra, extra > 0
+}
@@ -0,0 +1,126 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// appRenewBefore is how long before an installation token expires it is
+// replaced with a new one.
+const appRenewBefore = 5 * time.Minute
+
+// appToken is a GitHub App installation access token. These expire after an
+// hour, so it is minted again as expiry nears; a long run, or one paused for
+// a rate-limit reset, keeps working.
+type appToken struct {
+	clientOpts ClientOptions
+	key        *rsa.PrivateKey
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// appInstallationToken authenticates as a GitHub App with a JWT signed by
+// its private key and exchanges it for an installation access token.
+func appInstallationToken(ctx context.Context, clientOpts ClientOptions) (*appToken, error) {
+	opts := clientOpts.Auth
+	if opts.AppID == 0 || opts.AppInstallationID == 0 || opts.AppKeyFile == "" {
+		return nil, fmt.Errorf("GitHub App auth needs --app-id, --app-installation-id and --app-key-file")
+	}
+
+	key, err := loadAppKey(opts.AppKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	a := &appToken{clientOpts: clientOpts, key: key}
+	if _, err := a.get(ctx); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// get returns the current token, minting a new one if it is about to
+// expire.
+func (a *appToken) get(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if time.Until(a.expires) > appRenewBefore {
+		return a.token, nil
+	}
+
+	opts := a.clientOpts.Auth
+	jwt, err := signAppJWT(opts.AppID, a.key, time.Now())
+	if err != nil {
+		return "", err
+	}
+	client, err := newBaseClient(nil, a.clientOpts)
+	if err != nil {
+		return "", err
+	}
+	tok, _, err := client.WithAuthToken(jwt).Apps.CreateInstallationToken(ctx, opts.AppInstallationID, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create installation token for app %d: %w", opts.AppID, err)
+	}
+	a.token = tok.GetToken()
+	a.expires = tok.GetExpiresAt().Time
+	if a.expires.IsZero() {
+		a.expires = time.Now().Add(time.Hour)
+	}
+	return a.token, nil
+}
+
+func loadAppKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read app private key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("app private key %s is not PEM encoded", path)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse app private key %s: %w", path, err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("app private key %s is not an RSA key", path)
+	}
+	return key, nil
+}
+
+// signAppJWT builds the RS256 JWT GitHub expects from apps. It is backdated
+// a minute to allow for clock drift and expires within the 10-minute maximum.
+func signAppJWT(appID int64, key *rsa.PrivateKey, now time.Time) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.FormatInt(appID, 10),
+	})
+
+	enc := base64.RawURLEncoding
+	signingInput := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign app JWT: %w", err)
+	}
+	return signingInput + "." + enc.EncodeToString(sig), nil
+}
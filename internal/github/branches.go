@@ -0,0 +1,80 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	gh "github.com/google/go-github/v68/github"
+)
+
+// Branch is a branch name and the commit at its tip.
+type Branch struct {
+	Name string
+	SHA  string
+}
+
+// ListBranches returns all branches of a fork.
+func ListBranches(ctx context.Context, client *gh.Client, sched *Scheduler, fork ForkInfo) ([]Branch, error) {
+	var branches []Branch
+	opts := &gh.BranchListOptions{ListOptions: gh.ListOptions{PerPage: 100}}
+	for {
+		var page []*gh.Branch
+		var resp *gh.Response
+		err := call(ctx, sched, func() (*gh.Response, error) {
+			var err error
+			page, resp, err = client.Repositories.ListBranches(ctx, fork.Owner, fork.Repo, opts)
+			return resp, err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list branches of %s/%s: %w", fork.Owner, fork.Repo, err)
+		}
+		for _, b := range page {
+			branches = append(branches, Branch{Name: b.GetName(), SHA: b.GetCommit().GetSHA()})
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return branches, nil
+}
+
+// SelectBranches picks the branches to compare for a fork: its default
+// branch followed by those matching any of patterns ("all" matches every
+// branch). Branches whose head is the same commit as one already selected
+// are dropped, since comparing them would yield identical results.
+func SelectBranches(fork ForkInfo, branches []Branch, patterns []string) []string {
+	selected := []string{fork.DefaultBranch}
+	seenName := map[string]bool{fork.DefaultBranch: true}
+	seenSHA := make(map[string]bool)
+	for _, b := range branches {
+		if b.Name == fork.DefaultBranch {
+			seenSHA[b.SHA] = true
+		}
+	}
+
+	for _, b := range branches {
+		if seenName[b.Name] || seenSHA[b.SHA] || !matchBranch(b.Name, patterns) {
+			continue
+		}
+		seenName[b.Name] = true
+		seenSHA[b.SHA] = true
+		selected = append(selected, b.Name)
+	}
+	return selected
+}
+
+func matchBranch(name string, patterns []string) bool {
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "all" {
+			return true
+		}
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -1,30 +1,249 @@
 package github
 
 import (
+	"bufio"
 	"context"
 	"fmt"
+	"net/http"
+	"net/url"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
 	gh "github.com/google/go-github/v68/github"
 )
 
-func NewClient(ctx context.Context) (*gh.Client, error) {
-	token, err := getGHToken()
+// ClientOptions configures NewClient.
+type ClientOptions struct {
+	CacheDir  string   // on-disk HTTP cache location; empty disables caching
+	Tokens    []string // tokens to use; resolved from Auth when empty
+	BaseURL   string   // REST API URL for GitHub Enterprise Server; empty for github.com
+	UploadURL string   // upload URL for GitHub Enterprise Server; defaults to BaseURL
+	Auth      AuthOptions
+}
+
+// Host returns the GitHub hostname the options point at.
+func (o ClientOptions) Host() string {
+	if o.BaseURL == "" {
+		return "github.com"
+	}
+	u, err := url.Parse(o.BaseURL)
+	if err != nil || u.Host == "" {
+		return "github.com"
+	}
+	return u.Host
+}
+
+// AuthOptions holds the explicitly configured authentication sources.
+type AuthOptions struct {
+	TokenFile         string // file containing a token
+	AppID             int64  // GitHub App ID
+	AppInstallationID int64  // GitHub App installation to act as
+	AppKeyFile        string // GitHub App private key (PEM)
+}
+
+// Credentials are resolved tokens and a description of where they came
+// from. Several tokens are used as a pool, spreading requests across them.
+type Credentials struct {
+	Tokens []string
+	Source string
+
+	app *appToken // renews a GitHub App token before it expires; nil for other sources
+}
+
+func NewClient(ctx context.Context, opts ClientOptions) (*gh.Client, error) {
+	tokens := opts.Tokens
+	var app *appToken
+	if len(tokens) == 0 {
+		creds, err := ResolveCredentials(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		tokens, app = creds.Tokens, creds.app
+	}
+
+	var transport http.RoundTripper = http.DefaultTransport
+	if opts.CacheDir != "" {
+		cache, err := NewCacheTransport(opts.CacheDir, transport)
+		if err != nil {
+			return nil, err
+		}
+		transport = cache
+	}
+	pool := NewTokenPool(tokens, transport)
+	if app != nil {
+		pool.tokens[0].app = app
+	}
+	return newBaseClient(&http.Client{Transport: pool}, opts)
+}
+
+// newBaseClient returns an unauthenticated client for github.com or, when
+// BaseURL is set, for a GitHub Enterprise Server instance.
+func newBaseClient(httpClient *http.Client, opts ClientOptions) (*gh.Client, error) {
+	client := gh.NewClient(httpClient)
+	if opts.BaseURL == "" {
+		return client, nil
+	}
+	upload := opts.UploadURL
+	if upload == "" {
+		upload = opts.BaseURL
+	}
+	client, err := client.WithEnterpriseURLs(opts.BaseURL, upload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API URL %s: %w", opts.BaseURL, err)
+	}
+	return client, nil
+}
+
+// ResolveCredentials finds tokens by trying, in order: the explicitly
+// configured sources, the token file and then GitHub App installation auth;
+// the GITHUB_TOKENS, GITHUB_TOKEN and GH_TOKEN environment variables;
+// ~/.netrc; and finally the gh CLI. GITHUB_TOKENS and the token file may
+// hold several tokens. Sources that were explicitly configured but fail
+// return an error rather than falling through.
+func ResolveCredentials(ctx context.Context, clientOpts ClientOptions) (Credentials, error) {
+	opts := clientOpts.Auth
+	host := clientOpts.Host()
+
+	if opts.TokenFile != "" {
+		data, err := os.ReadFile(opts.TokenFile)
+		if err != nil {
+			return Credentials{}, fmt.Errorf("failed to read token file: %w", err)
+		}
+		tokens := splitTokens(string(data))
+		if len(tokens) == 0 {
+			return Credentials{}, fmt.Errorf("token file %s is empty", opts.TokenFile)
+		}
+		return Credentials{Tokens: tokens, Source: "token file " + opts.TokenFile}, nil
+	}
+
+	if opts.AppID != 0 || opts.AppKeyFile != "" || opts.AppInstallationID != 0 {
+		app, err := appInstallationToken(ctx, clientOpts)
+		if err != nil {
+			return Credentials{}, err
+		}
+		return Credentials{
+			Tokens: []string{app.token},
+			Source: fmt.Sprintf("GitHub App %d installation %d", opts.AppID, opts.AppInstallationID),
+			app:    app,
+		}, nil
+	}
+
+	for _, name := range []string{"GITHUB_TOKENS", "GITHUB_TOKEN", "GH_TOKEN"} {
+		if tokens := splitTokens(os.Getenv(name)); len(tokens) > 0 {
+			return Credentials{Tokens: tokens, Source: name + " environment variable"}, nil
+		}
+	}
+
+	netrcHosts := []string{host}
+	if host == "github.com" {
+		netrcHosts = []string{"api.github.com", "github.com"}
+	}
+	if token, path := netrcToken(netrcHosts...); token != "" {
+		return Credentials{Tokens: []string{token}, Source: "netrc file " + path}, nil
+	}
+
+	token, err := getGHToken(host)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("no GitHub credentials found (tried --token-file, GitHub App, GITHUB_TOKEN, GH_TOKEN, ~/.netrc, gh CLI): %w", err)
+	}
+	return Credentials{Tokens: []string{token}, Source: "GitHub CLI (gh auth token --hostname " + host + ")"}, nil
+}
+
+// splitTokens splits a list of tokens separated by whitespace or commas.
+func splitTokens(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\n' || r == '\r' || r == '\t'
+	})
+}
+
+// netrcToken returns the password of the first netrc entry matching one of
+// hosts, along with the path of the file it was read from.
+func netrcToken(hosts ...string) (string, string) {
+	path := os.Getenv("NETRC")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", ""
+		}
+		path = filepath.Join(home, ".netrc")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", ""
+	}
+	defer f.Close()
+
+	var fields []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields = append(fields, strings.Fields(scanner.Text())...)
+	}
+
+	passwords := make(map[string]string)
+	var machine string
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "machine":
+			if i+1 < len(fields) {
+				machine = fields[i+1]
+				i++
+			}
+		case "default":
+			machine = ""
+		case "password":
+			if i+1 < len(fields) && machine != "" {
+				passwords[machine] = fields[i+1]
+				i++
+			}
+		}
+	}
+	for _, h := range hosts {
+		if token := passwords[h]; token != "" {
+			return token, path
+		}
+	}
+	return "", ""
+}
+
+// TokenInfo describes what a token can do, as reported by the API.
+type TokenInfo struct {
+	Login     string // empty for tokens not tied to a user, such as app installations
+	Scopes    string // X-OAuth-Scopes; empty for fine-grained and app tokens
+	Limit     int
+	Remaining int
+}
+
+// InspectToken reports the user, scopes and rate limit of the client's token.
+func InspectToken(ctx context.Context, client *gh.Client) (*TokenInfo, error) {
+	req, err := client.NewRequest("GET", "rate_limit", nil)
 	if err != nil {
 		return nil, err
 	}
-	return gh.NewClient(nil).WithAuthToken(token), nil
+	resp, err := client.Do(ctx, req, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check token: %w", err)
+	}
+	info := &TokenInfo{
+		Scopes:    resp.Header.Get("X-OAuth-Scopes"),
+		Limit:     resp.Rate.Limit,
+		Remaining: resp.Rate.Remaining,
+	}
+	if user, _, err := client.Users.Get(ctx, ""); err == nil {
+		info.Login = user.GetLogin()
+	}
+	return info, nil
 }
 
-func getGHToken() (string, error) {
-	cmd := exec.Command("gh", "auth", "token")
+func getGHToken(host string) (string, error) {
+	cmd := exec.Command("gh", "auth", "token", "--hostname", host)
 	out, err := cmd.Output()
 	if err != nil {
 		if execErr, ok := err.(*exec.Error); ok && execErr.Err == exec.ErrNotFound {
 			return "", fmt.Errorf("GitHub CLI (gh) is not installed. Install it from https://cli.github.com/")
 		}
-		return "", fmt.Errorf("failed to get GitHub token — run 'gh auth login' first: %w", err)
+		return "", fmt.Errorf("failed to get GitHub token for %s — run 'gh auth login --hostname %s' first: %w", host, host, err)
 	}
 	token := strings.TrimSpace(string(out))
 	if token == "" {
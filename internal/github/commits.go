@@ -0,0 +1,69 @@
+package github
+
+import (
+	"context"
+	"fmt"
+
+	gh "github.com/google/go-github/v68/github"
+	"github.com/stympy/forkwatch/internal/diff"
+)
+
+// maxPatchIDCommits caps how many commits per comparison are fetched
+// individually for their patch-id and files. Each needs its own request, so
+// only the most recent ones are covered.
+const maxPatchIDCommits = 10
+
+// CommitDetails fills in the patch-id of each of the comparison's commits
+// and the upstream paths it touched. Both stay unknown ("" and nil) for
+// merge commits and commits beyond maxPatchIDCommits; the patch-id is also
+// unknown for commits whose diff GitHub doesn't return in full. Each commit
+// costs a request, so this is only worth doing for forks whose changes
+// overlap another's.
+func CommitDetails(ctx context.Context, client *gh.Client, sched *Scheduler, comp *ForkComparison) error {
+	ids := make([]string, len(comp.CommitSHAs))
+	files := make([][]string, len(comp.CommitSHAs))
+	first := max(0, len(comp.CommitSHAs)-maxPatchIDCommits)
+	for i := first; i < len(comp.CommitSHAs); i++ {
+		if i < len(comp.CommitMerges) && comp.CommitMerges[i] {
+			continue
+		}
+		var err error
+		if ids[i], files[i], err = commitDetail(ctx, client, sched, comp.Fork.Owner, comp.Fork.Repo, comp.CommitSHAs[i]); err != nil {
+			return err
+		}
+	}
+	comp.PatchIDs, comp.CommitFiles, comp.Detailed = ids, files, true
+	return nil
+}
+
+func commitDetail(ctx context.Context, client *gh.Client, sched *Scheduler, owner, repo, sha string) (id string, paths []string, err error) {
+	var commit *gh.RepositoryCommit
+	err = call(ctx, sched, func() (*gh.Response, error) {
+		var resp *gh.Response
+		var err error
+		commit, resp, err = client.Repositories.GetCommit(ctx, owner, repo, sha, nil)
+		return resp, err
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to fetch commit %s of %s/%s: %w", sha, owner, repo, err)
+	}
+	if len(commit.Files) >= maxCompareFiles {
+		return "", nil, nil
+	}
+
+	var patches []*diff.Patch
+	complete := true
+	for _, f := range commit.Files {
+		change := newFileChange(f)
+		paths = append(paths, change.SourcePath())
+		if p := change.Diff(); p != nil {
+			patches = append(patches, p)
+		} else {
+			complete = false
+		}
+	}
+	if !complete || len(patches) == 0 {
+		return "", paths, nil
+	}
+	return diff.PatchID(patches), paths, nil
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+	"time"
 
 	gh "github.com/google/go-github/v68/github"
+	"github.com/stympy/forkwatch/internal/diff"
 )
 
 var botAccounts = map[string]bool{
@@ -32,28 +34,50 @@ var boringFiles = map[string]bool{
 
 type ForkComparison struct {
 	Fork           ForkInfo
+	Branch         string // branch of the fork that was compared
 	AheadBy        int
 	CommitMessages []string
+	CommitSHAs     []string
+	CommitAuthors  []string    // GitHub login of each commit's author, or the git author name
+	CommitDates    []time.Time // author date of each commit
+	CommitMerges   []bool      // whether each commit is a merge
+	CommitFiles    [][]string  // upstream paths each commit touched; nil if unknown
+	PatchIDs       []string    // git patch-id of each commit in CommitSHAs; "" if unknown
+	Detailed       bool        // CommitFiles and PatchIDs have been fetched; see CommitDetails
 	FilesChanged   []FileChange
 }
 
 type FileChange struct {
-	Filename  string
-	Additions int
-	Deletions int
-	Patch     string
+	Filename         string
+	PreviousFilename string // the path before a rename or copy
+	Status           string // added, removed, modified, renamed, copied or changed
+	OldMode, NewMode string // e.g. "100644"; empty when unknown or absent
+	Additions        int
+	Deletions        int
+	Patch            string
+	Recomputed       bool // Patch was computed locally because GitHub omitted it
+	Binary           bool // the content changed but there is no textual diff
 }
 
-func CompareFork(ctx context.Context, client *gh.Client, upstreamOwner, upstreamRepo, upstreamBranch string, fork ForkInfo) (*ForkComparison, error) {
-	head := fmt.Sprintf("%s:%s", fork.Owner, fork.DefaultBranch)
+// CompareFork compares the fork's default branch to upstream.
+func CompareFork(ctx context.Context, client *gh.Client, sched *Scheduler, upstreamOwner, upstreamRepo, upstreamBranch string, fork ForkInfo) (*ForkComparison, error) {
+	return CompareBranch(ctx, client, sched, upstreamOwner, upstreamRepo, upstreamBranch, fork, fork.DefaultBranch)
+}
 
-	comparison, resp, err := client.Repositories.CompareCommits(ctx, upstreamOwner, upstreamRepo, upstreamBranch, head, nil)
-	if resp != nil && resp.Rate.Remaining < 10 {
-		return nil, fmt.Errorf("rate limit nearly exhausted (%d remaining, resets at %s) — stopping to avoid 403s",
-			resp.Rate.Remaining, resp.Rate.Reset.Time.Format("15:04:05"))
-	}
+// CompareBranch compares one branch of a fork to upstream. It returns nil
+// when the branch has no meaningful changes.
+func CompareBranch(ctx context.Context, client *gh.Client, sched *Scheduler, upstreamOwner, upstreamRepo, upstreamBranch string, fork ForkInfo, branch string) (*ForkComparison, error) {
+	head := fmt.Sprintf("%s:%s", fork.Owner, branch)
+
+	var comparison *gh.CommitsComparison
+	err := call(ctx, sched, func() (*gh.Response, error) {
+		var resp *gh.Response
+		var err error
+		comparison, resp, err = client.Repositories.CompareCommits(ctx, upstreamOwner, upstreamRepo, upstreamBranch, head, nil)
+		return resp, err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to compare %s/%s: %w", fork.Owner, fork.Repo, err)
+		return nil, fmt.Errorf("failed to compare %s/%s@%s: %w", fork.Owner, fork.Repo, branch, err)
 	}
 
 	aheadBy := comparison.GetAheadBy()
@@ -61,36 +85,61 @@ func CompareFork(ctx context.Context, client *gh.Client, upstreamOwner, upstream
 		return nil, nil
 	}
 
+	commits, err := allCommits(ctx, client, sched, upstreamOwner, upstreamRepo, upstreamBranch, head, comparison)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare %s/%s@%s: %w", fork.Owner, fork.Repo, branch, err)
+	}
+
 	// Check for bot-only commits
 	allBots := true
-	var messages []string
-	for _, c := range comparison.Commits {
+	var messages, shas, authors []string
+	var dates []time.Time
+	var merges []bool
+	for _, c := range commits {
 		author := c.GetCommit().GetAuthor().GetName()
 		if !botAccounts[author] {
 			allBots = false
 		}
 		msg := strings.Split(c.GetCommit().GetMessage(), "\n")[0]
 		messages = append(messages, msg)
+		shas = append(shas, c.GetSHA())
+		if login := c.GetAuthor().GetLogin(); login != "" {
+			author = login
+		}
+		authors = append(authors, author)
+		dates = append(dates, c.GetCommit().GetAuthor().GetDate().Time)
+		merges = append(merges, len(c.Parents) > 1)
 	}
-	if allBots && len(comparison.Commits) > 0 {
+	if allBots && len(commits) > 0 {
 		return nil, nil
 	}
 
-	// Check for boring-only file changes
 	var files []FileChange
-	allBoring := true
 	for _, f := range comparison.Files {
-		name := f.GetFilename()
-		baseName := filepath.Base(name)
-		if !boringFiles[baseName] && !isCI(name) {
+		files = append(files, newFileChange(f))
+	}
+	if len(commits) > 0 {
+		filler := &patchFiller{
+			ctx:           ctx,
+			client:        client,
+			sched:         sched,
+			upstreamOwner: upstreamOwner,
+			upstreamRepo:  upstreamRepo,
+			fork:          fork,
+			baseSHA:       comparison.GetMergeBaseCommit().GetSHA(),
+			headSHA:       commits[len(commits)-1].GetSHA(),
+		}
+		if files, err = filler.complete(files); err != nil {
+			return nil, fmt.Errorf("failed to complete comparison of %s/%s@%s: %w", fork.Owner, fork.Repo, branch, err)
+		}
+	}
+
+	// Check for boring-only file changes
+	allBoring := true
+	for _, f := range files {
+		if !boringFiles[filepath.Base(f.Filename)] && !isCI(f.Filename) {
 			allBoring = false
 		}
-		files = append(files, FileChange{
-			Filename:  name,
-			Additions: f.GetAdditions(),
-			Deletions: f.GetDeletions(),
-			Patch:     f.GetPatch(),
-		})
 	}
 	if allBoring && len(files) > 0 {
 		return nil, nil
@@ -98,15 +147,125 @@ func CompareFork(ctx context.Context, client *gh.Client, upstreamOwner, upstream
 
 	return &ForkComparison{
 		Fork:           fork,
+		Branch:         branch,
 		AheadBy:        aheadBy,
 		CommitMessages: messages,
+		CommitSHAs:     shas,
+		CommitAuthors:  authors,
+		CommitDates:    dates,
+		CommitMerges:   merges,
 		FilesChanged:   files,
 	}, nil
 }
 
+func newFileChange(f *gh.CommitFile) FileChange {
+	return FileChange{
+		Filename:         f.GetFilename(),
+		PreviousFilename: f.GetPreviousFilename(),
+		Status:           f.GetStatus(),
+		Additions:        f.GetAdditions(),
+		Deletions:        f.GetDeletions(),
+		Patch:            f.GetPatch(),
+		Binary:           isBinaryChange(f),
+	}
+}
+
+// isBinaryChange reports whether GitHub left out a file's patch because the
+// content is binary: it says nothing about line counts then. Renames and
+// mode changes legitimately have no patch, as does an empty file.
+func isBinaryChange(f *gh.CommitFile) bool {
+	if f.GetPatch() != "" || f.GetChanges() > 0 || f.GetSHA() == emptyBlob {
+		return false
+	}
+	switch f.GetStatus() {
+	case "renamed", "changed", "unchanged":
+		return false
+	}
+	return true
+}
+
+// SourcePath returns the file's path in upstream, which differs from
+// Filename for a rename.
+func (f FileChange) SourcePath() string {
+	if f.Status == "renamed" && f.PreviousFilename != "" {
+		return f.PreviousFilename
+	}
+	return f.Filename
+}
+
+// Diff builds the file's patch from GitHub's hunks and the file's status
+// and modes. It returns nil when the change can't be expressed as a patch,
+// e.g. for a binary file; a patch that can't be parsed is treated the same
+// way.
+func (f FileChange) Diff() *diff.Patch {
+	p := &diff.Patch{}
+	if f.Patch != "" {
+		var err error
+		if p, err = diff.Parse(f.Filename, f.Patch); err != nil {
+			return nil
+		}
+	} else if f.Binary || f.Additions+f.Deletions > 0 {
+		return nil
+	}
+
+	p.OldPath, p.NewPath = f.SourcePath(), f.Filename
+	switch f.Status {
+	case "added":
+		p.OldPath = ""
+	case "removed":
+		p.NewPath = ""
+	}
+	p.OldMode, p.NewMode = f.OldMode, f.NewMode
+	if p.Empty() {
+		return nil
+	}
+	return p
+}
+
 func isCI(path string) bool {
 	return strings.HasPrefix(path, ".github/") ||
 		strings.HasPrefix(path, ".circleci/") ||
 		strings.HasPrefix(path, ".travis") ||
 		strings.HasPrefix(path, ".gitlab-ci")
 }
+
+// BranchHead returns the SHA of the commit at the tip of a branch.
+func BranchHead(ctx context.Context, client *gh.Client, sched *Scheduler, owner, repo, branch string) (string, error) {
+	var b *gh.Branch
+	err := call(ctx, sched, func() (*gh.Response, error) {
+		var resp *gh.Response
+		var err error
+		b, resp, err = client.Repositories.GetBranch(ctx, owner, repo, branch, 1)
+		return resp, err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch branch %s of %s/%s: %w", branch, owner, repo, err)
+	}
+	return b.GetCommit().GetSHA(), nil
+}
+
+// CommitsBetween returns the SHAs of commits reachable from head but not
+// from base in the same repository.
+func CommitsBetween(ctx context.Context, client *gh.Client, sched *Scheduler, owner, repo, base, head string) ([]string, error) {
+	var comparison *gh.CommitsComparison
+	err := call(ctx, sched, func() (*gh.Response, error) {
+		var resp *gh.Response
+		var err error
+		comparison, resp, err = client.Repositories.CompareCommits(ctx, owner, repo, base, head, nil)
+		return resp, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare %s...%s in %s/%s: %w", base, head, owner, repo, err)
+	}
+	if comparison.GetBehindBy() > 0 {
+		return nil, fmt.Errorf("%s is not an ancestor of %s in %s/%s", base, head, owner, repo)
+	}
+	if comparison.GetTotalCommits() > len(comparison.Commits) {
+		return nil, fmt.Errorf("too many commits between %s and %s in %s/%s", base, head, owner, repo)
+	}
+	var shas []string
+	for _, c := range comparison.Commits {
+		shas = append(shas, c.GetSHA())
+	}
+	return shas, nil
+}
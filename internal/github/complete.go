@@ -0,0 +1,267 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	gh "github.com/google/go-github/v68/github"
+	"github.com/stympy/forkwatch/internal/diff"
+)
+
+// The compare API returns at most this many files; anything beyond is
+// silently dropped.
+const maxCompareFiles = 300
+
+// emptyBlob is git's object ID for a file with no content.
+const emptyBlob = "e69de29bb2d1d6434b8b29ae775ad8c2e48c5391"
+
+// maxBlobSize is the largest file forkwatch will download to recompute a
+// missing patch.
+const maxBlobSize = 1 << 20
+
+// allCommits returns every commit in the comparison. The compare API
+// embeds at most 250 commits; the rest are fetched page by page.
+func allCommits(ctx context.Context, client *gh.Client, sched *Scheduler, owner, repo, base, head string, comparison *gh.CommitsComparison) ([]*gh.RepositoryCommit, error) {
+	total := comparison.GetTotalCommits()
+	if len(comparison.Commits) >= total {
+		return comparison.Commits, nil
+	}
+
+	var commits []*gh.RepositoryCommit
+	opts := &gh.ListOptions{PerPage: 100, Page: 1}
+	for len(commits) < total {
+		var page *gh.CommitsComparison
+		var resp *gh.Response
+		err := call(ctx, sched, func() (*gh.Response, error) {
+			var err error
+			page, resp, err = client.Repositories.CompareCommits(ctx, owner, repo, base, head, opts)
+			return resp, err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to page through commits: %w", err)
+		}
+		if len(page.Commits) == 0 {
+			break
+		}
+		commits = append(commits, page.Commits...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return commits, nil
+}
+
+// patchFiller fills in what the compare API leaves out: files beyond its
+// 300-file cap, patches it omits for large files, and the file modes needed
+// to write patches that create, delete or chmod files. The missing pieces
+// come from the trees and blobs at the merge base and the fork's head.
+type patchFiller struct {
+	ctx    context.Context
+	client *gh.Client
+	sched  *Scheduler
+
+	upstreamOwner, upstreamRepo string
+	fork                        ForkInfo
+	baseSHA, headSHA            string
+
+	baseTree, headTree map[string]treeBlob // by path, loaded on demand
+	truncated          bool                // GitHub left entries out of either tree
+}
+
+type treeBlob struct {
+	sha, mode string
+}
+
+// complete returns files with truncation undone and missing patches
+// recomputed where possible.
+func (p *patchFiller) complete(files []FileChange) ([]FileChange, error) {
+	listed := len(files)
+	if listed >= maxCompareFiles {
+		extra, err := p.missingFiles(files)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, extra...)
+	}
+
+	for i, f := range files {
+		if needsModes(f) {
+			if err := p.setModes(&files[i]); err != nil {
+				return nil, err
+			}
+		}
+		// Files recovered from the trees come with no counts at all.
+		if i < listed && (f.Patch != "" || f.Additions+f.Deletions == 0) {
+			continue
+		}
+		if err := p.fill(&files[i]); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// needsModes reports whether a patch for the file has to state its mode.
+func needsModes(f FileChange) bool {
+	switch f.Status {
+	case "added", "removed", "changed":
+		return true
+	}
+	return false
+}
+
+func (p *patchFiller) setModes(f *FileChange) error {
+	if err := p.loadTrees(); err != nil {
+		return err
+	}
+	f.OldMode = p.baseTree[f.SourcePath()].mode
+	f.NewMode = p.headTree[f.Filename].mode
+	return nil
+}
+
+// missingFiles lists files changed between the merge base and head that the
+// compare API left out, by diffing the two trees. Nothing is recovered when
+// a tree is too large for GitHub to return whole, since every path it left
+// out would look added or removed.
+func (p *patchFiller) missingFiles(listed []FileChange) ([]FileChange, error) {
+	if err := p.loadTrees(); err != nil {
+		return nil, err
+	}
+	if p.truncated {
+		return nil, nil
+	}
+	seen := make(map[string]bool, len(listed))
+	for _, f := range listed {
+		seen[f.Filename] = true
+		if f.PreviousFilename != "" {
+			seen[f.PreviousFilename] = true
+		}
+	}
+
+	// Renames aren't detected here; they show up as a removal and an addition.
+	var extra []FileChange
+	for path, head := range p.headTree {
+		base, inBase := p.baseTree[path]
+		switch {
+		case seen[path] || base == head:
+		case !inBase:
+			extra = append(extra, FileChange{Filename: path, Status: "added"})
+		case base.sha == head.sha:
+			extra = append(extra, FileChange{Filename: path, Status: "changed"})
+		default:
+			extra = append(extra, FileChange{Filename: path, Status: "modified"})
+		}
+	}
+	for path := range p.baseTree {
+		if _, ok := p.headTree[path]; !ok && !seen[path] {
+			extra = append(extra, FileChange{Filename: path, Status: "removed"})
+		}
+	}
+	return extra, nil
+}
+
+// fill computes the patch and line counts for a file from its blobs.
+// Binary and oversized files are left without a patch, as are files a
+// truncated tree left out.
+func (p *patchFiller) fill(f *FileChange) error {
+	if err := p.loadTrees(); err != nil {
+		return err
+	}
+	if p.truncated {
+		_, inBase := p.baseTree[f.SourcePath()]
+		_, inHead := p.headTree[f.Filename]
+		if !inBase && f.Status != "added" || !inHead && f.Status != "removed" {
+			return nil
+		}
+	}
+	old, oldOK, err := p.blob(p.upstreamOwner, p.upstreamRepo, p.baseTree[f.SourcePath()].sha)
+	if err != nil {
+		return err
+	}
+	new, newOK, err := p.blob(p.fork.Owner, p.fork.Repo, p.headTree[f.Filename].sha)
+	if err != nil {
+		return err
+	}
+	if !oldOK || !newOK {
+		f.Binary = true
+		return nil
+	}
+
+	patch := diff.Compute(f.Filename, string(old), string(new), 3)
+	if patch.Empty() {
+		return nil
+	}
+	f.Patch = patch.Body()
+	f.Recomputed = true
+	f.Additions, f.Deletions = patch.Stats()
+	return nil
+}
+
+func (p *patchFiller) loadTrees() error {
+	if p.baseTree != nil {
+		return nil
+	}
+	var err error
+	var baseTruncated, headTruncated bool
+	if p.baseTree, baseTruncated, err = p.tree(p.upstreamOwner, p.upstreamRepo, p.baseSHA); err != nil {
+		return err
+	}
+	if p.headTree, headTruncated, err = p.tree(p.fork.Owner, p.fork.Repo, p.headSHA); err != nil {
+		return err
+	}
+	p.truncated = baseTruncated || headTruncated
+	return nil
+}
+
+// tree returns the blobs in a commit's tree, keyed by path, and whether
+// GitHub truncated the listing.
+func (p *patchFiller) tree(owner, repo, sha string) (map[string]treeBlob, bool, error) {
+	var tree *gh.Tree
+	err := call(p.ctx, p.sched, func() (*gh.Response, error) {
+		var resp *gh.Response
+		var err error
+		tree, resp, err = p.client.Git.GetTree(p.ctx, owner, repo, sha, true)
+		return resp, err
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to fetch tree %s of %s/%s: %w", sha, owner, repo, err)
+	}
+	blobs := make(map[string]treeBlob)
+	for _, e := range tree.Entries {
+		if e.GetType() == "blob" {
+			blobs[e.GetPath()] = treeBlob{sha: e.GetSHA(), mode: e.GetMode()}
+		}
+	}
+	return blobs, tree.GetTruncated(), nil
+}
+
+// blob downloads a blob's content. A missing blob (the file doesn't exist
+// on that side) is empty; ok is false for binary or oversized content.
+func (p *patchFiller) blob(owner, repo, sha string) (data []byte, ok bool, err error) {
+	if sha == "" {
+		return nil, true, nil
+	}
+	err = call(p.ctx, p.sched, func() (*gh.Response, error) {
+		var resp *gh.Response
+		var err error
+		data, resp, err = p.client.Git.GetBlobRaw(p.ctx, owner, repo, sha)
+		return resp, err
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to fetch blob %s of %s/%s: %w", sha, owner, repo, err)
+	}
+	if len(data) > maxBlobSize || isBinary(data) {
+		return nil, false, nil
+	}
+	return data, true, nil
+}
+
+// isBinary uses git's heuristic: a NUL byte in the first 8000 bytes.
+func isBinary(data []byte) bool {
+	if len(data) > 8000 {
+		data = data[:8000]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
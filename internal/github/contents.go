@@ -0,0 +1,54 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	gh "github.com/google/go-github/v68/github"
+)
+
+// FileContent returns a file's content at ref. found is false when the
+// file doesn't exist there.
+func FileContent(ctx context.Context, client *gh.Client, sched *Scheduler, owner, repo, ref, path string) (content string, found bool, err error) {
+	var file *gh.RepositoryContent
+	err = call(ctx, sched, func() (*gh.Response, error) {
+		var resp *gh.Response
+		var err error
+		file, _, resp, err = client.Repositories.GetContents(ctx, owner, repo, path, &gh.RepositoryContentGetOptions{Ref: ref})
+		return resp, err
+	})
+	var respErr *gh.ErrorResponse
+	if errors.As(err, &respErr) && respErr.Response != nil && respErr.Response.StatusCode == http.StatusNotFound {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to fetch %s from %s/%s: %w", path, owner, repo, err)
+	}
+	if file == nil {
+		// A directory now sits at the path.
+		return "", false, nil
+	}
+
+	// The contents API leaves out files over 1 MB; the blob API has them.
+	if file.GetEncoding() == "none" {
+		var data []byte
+		err = call(ctx, sched, func() (*gh.Response, error) {
+			var resp *gh.Response
+			var err error
+			data, resp, err = client.Git.GetBlobRaw(ctx, owner, repo, file.GetSHA())
+			return resp, err
+		})
+		if err != nil {
+			return "", false, fmt.Errorf("failed to fetch %s from %s/%s: %w", path, owner, repo, err)
+		}
+		return string(data), true, nil
+	}
+
+	content, err = file.GetContent()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to decode %s from %s/%s: %w", path, owner, repo, err)
+	}
+	return content, true, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"strings"
 
 	gh "github.com/google/go-github/v68/github"
 )
@@ -14,14 +15,113 @@ type ForkInfo struct {
 	DefaultBranch string
 	PushedAt      gh.Timestamp
 	HTMLURL       string
+	Stars         int
+	Archived      bool
+	Disabled      bool
+	ForkCount     int
+	Parent        string // owner/repo this was forked from
+
+	// Only populated when forks are enumerated via GraphQL.
+	HeadOID   string // commit at the tip of the default branch
+	HasCounts bool   // whether AheadBy and BehindBy are known
+	AheadBy   int    // commits on the fork not in upstream
+	BehindBy  int    // commits in upstream not on the fork
+}
+
+// FetchOptions controls which forks FetchForks enumerates.
+type FetchOptions struct {
+	Limit   int  // max forks returned, keeping the most recently pushed
+	Depth   int  // levels of forks-of-forks to walk; 0 means no limit
+	Network bool // walk from the root of the fork network instead of owner/repo
 }
 
-func FetchForks(ctx context.Context, client *gh.Client, owner, repo string, limit int) ([]ForkInfo, *gh.Repository, error) {
-	upstream, _, err := client.Repositories.Get(ctx, owner, repo)
+func FetchForks(ctx context.Context, client *gh.Client, sched *Scheduler, owner, repo string, opts FetchOptions) ([]ForkInfo, *gh.Repository, error) {
+	var upstream *gh.Repository
+	err := call(ctx, sched, func() (*gh.Response, error) {
+		var resp *gh.Response
+		var err error
+		upstream, resp, err = client.Repositories.Get(ctx, owner, repo)
+		return resp, err
+	})
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to fetch repository %s/%s: %w", owner, repo, err)
 	}
 
+	upstreamBranch := upstream.GetDefaultBranch()
+	if upstreamBranch == "" {
+		upstreamBranch = "main"
+	}
+	upstreamRef := fmt.Sprintf("%s:%s", owner, upstreamBranch)
+
+	rootOwner, rootRepo := owner, repo
+	if src := upstream.GetSource(); opts.Network && src != nil {
+		rootOwner, rootRepo = src.GetOwner().GetLogin(), src.GetName()
+	}
+
+	type node struct {
+		owner, repo string
+		level       int
+	}
+	queue := []node{{rootOwner, rootRepo, 1}}
+	var all []ForkInfo
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+
+		children, err := listForks(ctx, client, sched, n.owner, n.repo, upstreamRef, opts.Limit)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, c := range children {
+			c.Parent = n.owner + "/" + n.repo
+			if (opts.Depth == 0 || n.level < opts.Depth) && c.ForkCount > 0 {
+				queue = append(queue, node{c.Owner, c.Repo, n.level + 1})
+			}
+			// The chosen upstream turns up among its siblings when walking
+			// the whole network; it is the base, not a fork to compare.
+			if strings.EqualFold(c.Owner, owner) && strings.EqualFold(c.Repo, repo) {
+				continue
+			}
+			all = append(all, c)
+		}
+	}
+
+	if rootOwner != owner || rootRepo != repo {
+		// In network mode the root is a relative of upstream like any other.
+		src := upstream.GetSource()
+		all = append(all, ForkInfo{
+			Owner:         rootOwner,
+			Repo:          rootRepo,
+			DefaultBranch: src.GetDefaultBranch(),
+			PushedAt:      src.GetPushedAt(),
+			HTMLURL:       src.GetHTMLURL(),
+			Stars:         src.GetStargazersCount(),
+			ForkCount:     src.GetForksCount(),
+		})
+	}
+
+	// Sort by most recently pushed across all levels, then apply limit
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].PushedAt.After(all[j].PushedAt.Time)
+	})
+	if len(all) > opts.Limit {
+		all = all[:opts.Limit]
+	}
+	return all, upstream, nil
+}
+
+// listForks returns the direct forks of owner/repo. GraphQL can order forks
+// by push date across all pages; REST can only order by creation date, so
+// it's the fallback when GraphQL is unavailable.
+func listForks(ctx context.Context, client *gh.Client, sched *Scheduler, owner, repo, upstreamRef string, limit int) ([]ForkInfo, error) {
+	forks, err := fetchForksGraphQL(ctx, client, sched, owner, repo, upstreamRef, limit)
+	if err != nil {
+		forks, err = fetchForksREST(ctx, client, sched, owner, repo, limit)
+	}
+	return forks, err
+}
+
+func fetchForksREST(ctx context.Context, client *gh.Client, sched *Scheduler, owner, repo string, limit int) ([]ForkInfo, error) {
 	var allForks []*gh.Repository
 	opts := &gh.RepositoryListForksOptions{
 		Sort:        "newest",
@@ -29,9 +129,15 @@ func FetchForks(ctx context.Context, client *gh.Client, owner, repo string, limi
 	}
 
 	for {
-		forks, resp, err := client.Repositories.ListForks(ctx, owner, repo, opts)
+		var forks []*gh.Repository
+		var resp *gh.Response
+		err := call(ctx, sched, func() (*gh.Response, error) {
+			var err error
+			forks, resp, err = client.Repositories.ListForks(ctx, owner, repo, opts)
+			return resp, err
+		})
 		if err != nil {
-			return nil, nil, fmt.Errorf("failed to list forks: %w", err)
+			return nil, fmt.Errorf("failed to list forks: %w", err)
 		}
 		allForks = append(allForks, forks...)
 		if resp.NextPage == 0 || len(allForks) >= limit {
@@ -63,8 +169,12 @@ func FetchForks(ctx context.Context, client *gh.Client, owner, repo string, limi
 			DefaultBranch: branch,
 			PushedAt:      f.GetPushedAt(),
 			HTMLURL:       f.GetHTMLURL(),
+			Stars:         f.GetStargazersCount(),
+			Archived:      f.GetArchived(),
+			Disabled:      f.GetDisabled(),
+			ForkCount:     f.GetForksCount(),
 		})
 	}
 
-	return results, upstream, nil
+	return results, nil
 }
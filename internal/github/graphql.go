@@ -0,0 +1,185 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	gh "github.com/google/go-github/v68/github"
+)
+
+// graphqlPageSize is kept below the 100-node maximum because every node
+// also computes an ahead/behind comparison, which is expensive server-side.
+const graphqlPageSize = 50
+
+const forksQuery = `query($owner: String!, $name: String!, $first: Int!, $after: String, $upstreamRef: String!) {
+  repository(owner: $owner, name: $name) {
+    forks(first: $first, after: $after, orderBy: {field: PUSHED_AT, direction: DESC}) {
+      pageInfo { hasNextPage endCursor }
+      nodes {
+        name
+        url
+        pushedAt
+        stargazerCount
+        isArchived
+        isDisabled
+        forkCount
+        owner { login }
+        defaultBranchRef {
+          name
+          target { oid }
+          compare(headRef: $upstreamRef) { aheadBy behindBy }
+        }
+      }
+    }
+  }
+}`
+
+type graphqlRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables"`
+}
+
+type graphqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+type forksData struct {
+	Repository *struct {
+		Forks struct {
+			PageInfo struct {
+				HasNextPage bool   `json:"hasNextPage"`
+				EndCursor   string `json:"endCursor"`
+			} `json:"pageInfo"`
+			Nodes []forkNode `json:"nodes"`
+		} `json:"forks"`
+	} `json:"repository"`
+}
+
+type forkNode struct {
+	Name           string    `json:"name"`
+	URL            string    `json:"url"`
+	PushedAt       time.Time `json:"pushedAt"`
+	StargazerCount int       `json:"stargazerCount"`
+	IsArchived     bool      `json:"isArchived"`
+	IsDisabled     bool      `json:"isDisabled"`
+	ForkCount      int       `json:"forkCount"`
+	Owner          struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+	DefaultBranchRef *struct {
+		Name   string `json:"name"`
+		Target struct {
+			OID string `json:"oid"`
+		} `json:"target"`
+		Compare *struct {
+			AheadBy  int `json:"aheadBy"`
+			BehindBy int `json:"behindBy"`
+		} `json:"compare"`
+	} `json:"defaultBranchRef"`
+}
+
+// fetchForksGraphQL enumerates forks through the GraphQL API, ordered by
+// most recent push, so that limit keeps the most recently active forks.
+// Ahead/behind counts are computed against upstreamRef ("owner:branch").
+func fetchForksGraphQL(ctx context.Context, client *gh.Client, sched *Scheduler, owner, repo, upstreamRef string, limit int) ([]ForkInfo, error) {
+	var results []ForkInfo
+	vars := map[string]any{
+		"owner":       owner,
+		"name":        repo,
+		"first":       graphqlPageSize,
+		"upstreamRef": upstreamRef,
+	}
+
+	for len(results) < limit {
+		var page forksData
+		if err := doGraphQL(ctx, client, sched, forksQuery, vars, &page); err != nil {
+			return nil, err
+		}
+		if page.Repository == nil {
+			return nil, fmt.Errorf("repository %s/%s not found via GraphQL", owner, repo)
+		}
+
+		forks := page.Repository.Forks
+		for _, n := range forks.Nodes {
+			results = append(results, n.forkInfo())
+		}
+		if !forks.PageInfo.HasNextPage {
+			break
+		}
+		vars["after"] = forks.PageInfo.EndCursor
+	}
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func (n forkNode) forkInfo() ForkInfo {
+	info := ForkInfo{
+		Owner:         n.Owner.Login,
+		Repo:          n.Name,
+		DefaultBranch: "main",
+		PushedAt:      gh.Timestamp{Time: n.PushedAt},
+		HTMLURL:       n.URL,
+		Stars:         n.StargazerCount,
+		Archived:      n.IsArchived,
+		Disabled:      n.IsDisabled,
+		ForkCount:     n.ForkCount,
+	}
+	if ref := n.DefaultBranchRef; ref != nil {
+		info.DefaultBranch = ref.Name
+		info.HeadOID = ref.Target.OID
+		// The fork's branch is the base of this comparison and upstream the
+		// head, so upstream's "ahead" is the fork's "behind" and vice versa.
+		if ref.Compare != nil {
+			info.HasCounts = true
+			info.AheadBy = ref.Compare.BehindBy
+			info.BehindBy = ref.Compare.AheadBy
+		}
+	}
+	return info
+}
+
+// doGraphQL posts a query to the GraphQL endpoint matching the client's
+// REST base URL and decodes the response data into v.
+func doGraphQL(ctx context.Context, client *gh.Client, sched *Scheduler, query string, vars map[string]any, v any) error {
+	// GraphQL has its own rate-limit bucket, so the response is not
+	// reported to the scheduler, which tracks the REST quota.
+	var resp graphqlResponse
+	err := call(ctx, sched, func() (*gh.Response, error) {
+		req, err := client.NewRequest("POST", graphqlURL(client), graphqlRequest{Query: query, Variables: vars})
+		if err != nil {
+			return nil, err
+		}
+		_, err = client.Do(ctx, req, &resp)
+		return nil, err
+	})
+	if err != nil {
+		return fmt.Errorf("GraphQL request failed: %w", err)
+	}
+	if len(resp.Errors) > 0 {
+		var msgs []string
+		for _, e := range resp.Errors {
+			msgs = append(msgs, e.Message)
+		}
+		return fmt.Errorf("GraphQL query failed: %s", strings.Join(msgs, "; "))
+	}
+	return json.Unmarshal(resp.Data, v)
+}
+
+// graphqlURL derives the GraphQL endpoint from the REST base URL:
+// api.github.com/graphql for github.com, /api/graphql for Enterprise Server.
+func graphqlURL(client *gh.Client) string {
+	base := client.BaseURL.String()
+	if strings.HasSuffix(base, "/api/v3/") {
+		return strings.TrimSuffix(base, "v3/") + "graphql"
+	}
+	return base + "graphql"
+}
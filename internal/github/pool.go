@@ -0,0 +1,152 @@
+package github
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenReserve is how many requests a token keeps in hand before the pool
+// prefers other tokens.
+const tokenReserve = 10
+
+// quota is one token's rate-limit state for one resource (core, graphql, ...).
+type quota struct {
+	limit     int
+	remaining int
+	reset     time.Time
+}
+
+type pooledToken struct {
+	token  string
+	app    *appToken // renews token when set
+	quotas map[string]*quota
+}
+
+// TokenPool is an http.RoundTripper that authenticates each request with
+// whichever token has the most remaining budget for the request's rate-limit
+// resource. Responses carry the pool's combined quota in their rate-limit
+// headers, so the Scheduler paces against all tokens together and only
+// pauses once every token is exhausted.
+type TokenPool struct {
+	Transport http.RoundTripper
+
+	mu     sync.Mutex
+	tokens []*pooledToken
+}
+
+// NewTokenPool returns a pool over tokens, sending requests via transport.
+func NewTokenPool(tokens []string, transport http.RoundTripper) *TokenPool {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	p := &TokenPool{Transport: transport}
+	for _, t := range tokens {
+		p.tokens = append(p.tokens, &pooledToken{token: t, quotas: make(map[string]*quota)})
+	}
+	return p
+}
+
+func (p *TokenPool) RoundTrip(req *http.Request) (*http.Response, error) {
+	resource := "core"
+	if strings.HasSuffix(req.URL.Path, "/graphql") {
+		resource = "graphql"
+	}
+
+	t := p.pick(resource)
+	token := t.token
+	if t.app != nil {
+		var err error
+		if token, err = t.app.get(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := p.Transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if r := resp.Header.Get("X-RateLimit-Resource"); r != "" {
+		resource = r
+	}
+	p.observe(t, resource, resp.Header)
+	return resp, nil
+}
+
+// pick chooses the token with the most remaining budget. Tokens whose quota
+// is unknown or whose window has reset count as full.
+func (p *TokenPool) pick(resource string) *pooledToken {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var best *pooledToken
+	bestRemaining := -1
+	for _, t := range p.tokens {
+		remaining := math.MaxInt32
+		if q := t.quotas[resource]; q != nil && now.Before(q.reset) {
+			remaining = q.remaining
+		}
+		if remaining > bestRemaining {
+			best, bestRemaining = t, remaining
+		}
+	}
+	// Count the request against the token until its response arrives, so
+	// concurrent workers spread across tokens.
+	if q := best.quotas[resource]; q != nil && now.Before(q.reset) {
+		q.remaining--
+	}
+	return best
+}
+
+// observe records a token's quota from response headers and rewrites the
+// headers to describe the pool as a whole: the summed remaining budget above
+// each token's reserve, and the earliest reset among exhausted tokens.
+func (p *TokenPool) observe(t *pooledToken, resource string, header http.Header) {
+	limit, err1 := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	remaining, err2 := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	reset, err3 := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t.quotas[resource] = &quota{limit: limit, remaining: remaining, reset: time.Unix(reset, 0)}
+	if len(p.tokens) == 1 {
+		return
+	}
+
+	now := time.Now()
+	totalLimit, totalRemaining := 0, 0
+	var earliest time.Time
+	for _, pt := range p.tokens {
+		q := pt.quotas[resource]
+		if q == nil || !now.Before(q.reset) {
+			// Unknown or reset: assume a full quota like the observed token's.
+			totalLimit += limit
+			totalRemaining += limit - tokenReserve
+			continue
+		}
+		totalLimit += q.limit
+		if q.remaining > tokenReserve {
+			totalRemaining += q.remaining - tokenReserve
+		} else if earliest.IsZero() || q.reset.Before(earliest) {
+			earliest = q.reset
+		}
+	}
+	if earliest.IsZero() || totalRemaining > 0 {
+		earliest = time.Unix(reset, 0)
+	}
+
+	// The Scheduler keeps its own reserve on top of the pool's.
+	header.Set("X-RateLimit-Limit", strconv.Itoa(totalLimit))
+	header.Set("X-RateLimit-Remaining", strconv.Itoa(totalRemaining+tokenReserve))
+	header.Set("X-RateLimit-Reset", strconv.FormatInt(earliest.Unix(), 10))
+}
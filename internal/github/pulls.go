@@ -0,0 +1,106 @@
+package github
+
+import (
+	"context"
+	"fmt"
+
+	gh "github.com/google/go-github/v68/github"
+)
+
+// maxPullRequests is how many of upstream's most recently updated pull
+// requests are checked against the forks. Each costs a request for its
+// files.
+const maxPullRequests = 100
+
+// PullRequest is an upstream pull request, open or closed.
+type PullRequest struct {
+	Number     int
+	Title      string
+	URL        string
+	Author     string
+	State      string // open, closed or merged
+	HeadOwner  string // owner of the repository the PR was opened from
+	HeadBranch string
+	Files      []FileChange
+}
+
+// ListPullRequests returns upstream's most recently updated pull requests
+// with the files each changes.
+func ListPullRequests(ctx context.Context, client *gh.Client, sched *Scheduler, owner, repo string) ([]PullRequest, error) {
+	opts := &gh.PullRequestListOptions{
+		State:       "all",
+		Sort:        "updated",
+		Direction:   "desc",
+		ListOptions: gh.ListOptions{PerPage: 100},
+	}
+
+	var prs []PullRequest
+	for len(prs) < maxPullRequests {
+		var page []*gh.PullRequest
+		var resp *gh.Response
+		err := call(ctx, sched, func() (*gh.Response, error) {
+			var err error
+			page, resp, err = client.PullRequests.List(ctx, owner, repo, opts)
+			return resp, err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pull requests of %s/%s: %w", owner, repo, err)
+		}
+		for _, pr := range page {
+			if len(prs) == maxPullRequests {
+				break
+			}
+			prs = append(prs, newPullRequest(pr))
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	for i := range prs {
+		files, err := pullRequestFiles(ctx, client, sched, owner, repo, prs[i].Number)
+		if err != nil {
+			return nil, err
+		}
+		prs[i].Files = files
+	}
+	return prs, nil
+}
+
+func newPullRequest(pr *gh.PullRequest) PullRequest {
+	state := pr.GetState()
+	if pr.MergedAt != nil {
+		state = "merged"
+	}
+	return PullRequest{
+		Number:     pr.GetNumber(),
+		Title:      pr.GetTitle(),
+		URL:        pr.GetHTMLURL(),
+		Author:     pr.GetUser().GetLogin(),
+		State:      state,
+		HeadOwner:  pr.GetHead().GetRepo().GetOwner().GetLogin(),
+		HeadBranch: pr.GetHead().GetRef(),
+	}
+}
+
+// pullRequestFiles returns the files a pull request changes. Only the first
+// page is fetched; PRs touching more files than that are rarely the small
+// fixes forkwatch looks for.
+func pullRequestFiles(ctx context.Context, client *gh.Client, sched *Scheduler, owner, repo string, number int) ([]FileChange, error) {
+	var files []*gh.CommitFile
+	err := call(ctx, sched, func() (*gh.Response, error) {
+		var resp *gh.Response
+		var err error
+		files, resp, err = client.PullRequests.ListFiles(ctx, owner, repo, number, &gh.ListOptions{PerPage: 100})
+		return resp, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files of pull request #%d: %w", number, err)
+	}
+	changes := make([]FileChange, 0, len(files))
+	for _, f := range files {
+		changes = append(changes, newFileChange(f))
+	}
+	return changes, nil
+}
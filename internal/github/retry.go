@@ -0,0 +1,123 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	gh "github.com/google/go-github/v68/github"
+)
+
+const (
+	maxAttempts = 5
+	baseBackoff = 2 * time.Second
+	maxBackoff  = 2 * time.Minute
+
+	// abuseBackoff is GitHub's recommended minimum wait after a secondary
+	// rate limit when the response doesn't say how long to wait.
+	abuseBackoff = time.Minute
+)
+
+// call runs one API request through the scheduler, retrying transient
+// failures with exponential backoff. Rate-limit errors hold back every
+// worker, not just the caller, until GitHub allows requests again.
+func call(ctx context.Context, sched *Scheduler, fn func() (*gh.Response, error)) error {
+	for attempt := 1; ; attempt++ {
+		if err := sched.Wait(ctx); err != nil {
+			return err
+		}
+		resp, err := fn()
+		sched.Observe(resp)
+		if err == nil {
+			return nil
+		}
+
+		delay, global, retry := retryDelay(err, resp, attempt)
+		if !retry || attempt == maxAttempts {
+			return err
+		}
+		if global {
+			sched.Hold(time.Now().Add(delay))
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// retryDelay decides whether a failed request should be retried and after
+// how long. global reports whether the wait applies to all requests.
+func retryDelay(err error, resp *gh.Response, attempt int) (delay time.Duration, global, retry bool) {
+	var rateErr *gh.RateLimitError
+	var abuseErr *gh.AbuseRateLimitError
+	var respErr *gh.ErrorResponse
+
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return 0, false, false
+	case errors.As(err, &rateErr):
+		return time.Until(rateErr.Rate.Reset.Time) + time.Second, true, true
+	case errors.As(err, &abuseErr):
+		if d := abuseErr.GetRetryAfter(); d > 0 {
+			return d, true, true
+		}
+		return abuseBackoff, true, true
+	case errors.As(err, &respErr):
+		if respErr.Response == nil || respErr.Response.StatusCode < 500 {
+			return 0, false, false
+		}
+	}
+
+	// Server errors and network failures: honor Retry-After if given,
+	// otherwise back off exponentially.
+	if resp != nil {
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			return time.Duration(secs) * time.Second, false, true
+		}
+	}
+	delay = baseBackoff << (attempt - 1)
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay, false, true
+}
+
+// FailureReason classifies an API error for reporting skipped forks.
+func FailureReason(err error) string {
+	var rateErr *gh.RateLimitError
+	var abuseErr *gh.AbuseRateLimitError
+	var respErr *gh.ErrorResponse
+
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "canceled"
+	case errors.As(err, &rateErr):
+		return "rate limited"
+	case errors.As(err, &abuseErr):
+		return "secondary rate limit"
+	case errors.As(err, &respErr) && respErr.Response != nil:
+		switch code := respErr.Response.StatusCode; {
+		case code == http.StatusNotFound:
+			return "not found"
+		case code >= 500:
+			return "server error"
+		}
+	}
+	return "error"
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
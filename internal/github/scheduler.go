@@ -0,0 +1,116 @@
+package github
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	gh "github.com/google/go-github/v68/github"
+)
+
+// paceBelow is the remaining-request count under which the scheduler starts
+// spreading requests evenly over the rest of the rate-limit window.
+const paceBelow = 200
+
+// Scheduler paces API requests from concurrent workers using the rate-limit
+// state GitHub reports on every response. Instead of failing when the quota
+// runs low it slows down, and once only the reserve is left it pauses until
+// the window resets.
+type Scheduler struct {
+	// OnPause, if set, is called whenever the scheduler decides to wait
+	// for the rate-limit window to reset.
+	OnPause func(until time.Time)
+
+	mu        sync.Mutex
+	reserve   int
+	known     bool
+	remaining int
+	reset     time.Time
+	next      time.Time // earliest start time for the next request
+}
+
+// NewScheduler returns a scheduler that keeps reserve requests of the
+// quota untouched.
+func NewScheduler(reserve int) *Scheduler {
+	return &Scheduler{reserve: reserve}
+}
+
+// Wait blocks until the caller may issue its next request. A nil Scheduler
+// never blocks.
+func (s *Scheduler) Wait(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	now := time.Now()
+	start := now
+	if s.next.After(start) {
+		start = s.next
+	}
+	var pausedUntil time.Time
+	if s.known && now.Before(s.reset) {
+		budget := s.remaining - s.reserve
+		switch {
+		case budget <= 0:
+			// Quota exhausted for this window; everyone waits for the reset.
+			pausedUntil = s.reset.Add(time.Second)
+			if pausedUntil.After(start) {
+				start = pausedUntil
+			}
+			s.next = start
+			s.known = false
+		case s.remaining < paceBelow:
+			s.next = start.Add(s.reset.Sub(now) / time.Duration(budget))
+			s.remaining--
+		default:
+			s.remaining--
+		}
+	}
+	onPause := s.OnPause
+	s.mu.Unlock()
+
+	if !pausedUntil.IsZero() && onPause != nil {
+		onPause(pausedUntil)
+	}
+
+	return sleep(ctx, time.Until(start))
+}
+
+// Hold makes every subsequent request wait until at least the given time,
+// e.g. after a secondary rate limit asks clients to back off.
+func (s *Scheduler) Hold(until time.Time) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	extended := until.After(s.next)
+	if extended {
+		s.next = until
+	}
+	onPause := s.OnPause
+	s.mu.Unlock()
+
+	if extended && onPause != nil {
+		onPause(until)
+	}
+}
+
+// Observe records the rate-limit state from a response.
+func (s *Scheduler) Observe(resp *gh.Response) {
+	if s == nil || resp == nil || resp.Rate.Limit == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reset := resp.Rate.Reset.Time
+	// Responses from concurrent workers can arrive out of order; within the
+	// same window the lowest remaining count is the most recent.
+	if s.known && reset.Equal(s.reset) && resp.Rate.Remaining > s.remaining {
+		return
+	}
+	s.known = true
+	s.remaining = resp.Rate.Remaining
+	s.reset = reset
+}
@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/stympy/forkwatch/internal/analysis"
+	"github.com/stympy/forkwatch/internal/diff"
 )
 
 type jsonOutput struct {
@@ -13,40 +15,119 @@ type jsonOutput struct {
 	TotalForks         int                  `json:"total_forks"`
 	Analyzed           int                  `json:"analyzed_forks"`
 	Active             int                  `json:"active_forks"`
+	Partial            bool                 `json:"partial,omitempty"`
+	NotReached         int                  `json:"forks_not_reached,omitempty"`
 	RecommendedChanges []jsonRecommendation `json:"recommended_changes,omitempty"`
 	Clusters           []jsonCluster        `json:"clusters"`
+	Skipped            []jsonSkipped        `json:"skipped_forks,omitempty"`
+}
+
+type jsonSkipped struct {
+	Owner  string `json:"owner"`
+	Reason string `json:"reason"`
+	Error  string `json:"error"`
 }
 
 type jsonRecommendation struct {
-	File          string   `json:"file"`
-	Patch         string   `json:"patch"`
-	Convergence   int      `json:"convergence"`
-	AgreedBy      int      `json:"agreed_by"`
-	Forks         []string `json:"forks"`
-	CommitMessage string   `json:"commit_message"`
+	File          string     `json:"file"`
+	Status        string     `json:"status"`
+	Patch         string     `json:"patch"`
+	Applies       string     `json:"applies,omitempty"`
+	Convergence   int        `json:"convergence"`
+	Raw           int        `json:"raw_convergence"`
+	AgreedBy      int        `json:"agreed_by"`
+	Forks         []string   `json:"forks"`
+	CommitMessage string     `json:"commit_message"`
+	PullRequests  []jsonPR   `json:"pull_requests,omitempty"`
+	HunkVotes     []jsonVote `json:"hunk_votes,omitempty"`
+}
+
+type jsonVote struct {
+	Patch    string   `json:"patch"`
+	Votes    int      `json:"votes"`
+	RawVotes int      `json:"raw_votes"`
+	Of       int      `json:"of"`
+	Forks    []string `json:"forks"`
+	Included bool     `json:"included"`
+}
+
+type jsonPR struct {
+	Number int      `json:"number"`
+	Title  string   `json:"title"`
+	URL    string   `json:"url"`
+	Author string   `json:"author"`
+	State  string   `json:"state"`
+	Forks  []string `json:"forks"`
 }
 
 type jsonCluster struct {
-	File        string           `json:"file"`
-	Convergence int              `json:"convergence"`
-	Forks       []jsonFork       `json:"forks"`
-	PatchGroups []jsonPatchGroup `json:"patch_groups,omitempty"`
+	File         string           `json:"file"`
+	Convergence  int              `json:"convergence"`
+	Raw          int              `json:"raw_convergence"`
+	Forks        []jsonFork       `json:"forks"`
+	PatchGroups  []jsonPatchGroup `json:"patch_groups,omitempty"`
+	Families     []jsonFamily     `json:"families,omitempty"`
+	PullRequests []jsonPR         `json:"pull_requests,omitempty"`
+	Hunks        []jsonHunk       `json:"hunks,omitempty"`
+}
+
+type jsonHunk struct {
+	OldStart    int      `json:"old_start"`
+	OldLines    int      `json:"old_lines"`
+	Patch       string   `json:"patch"`
+	Convergence int      `json:"convergence"`
+	Raw         int      `json:"raw_convergence"`
+	Forks       []string `json:"forks"`
+	Assembled   bool     `json:"recommended"`
 }
 
 type jsonFork struct {
-	Owner    string   `json:"owner"`
-	URL      string   `json:"url"`
-	AheadBy  int      `json:"ahead_by"`
-	Commits  []string `json:"commit_messages"`
-	Added    int      `json:"additions"`
-	Deleted  int      `json:"deletions"`
-	Patch    string   `json:"patch,omitempty"`
+	Owner      string   `json:"owner"`
+	Lineage    string   `json:"lineage"`
+	Branch     string   `json:"branch"`
+	Status     string   `json:"status"`
+	Path       string   `json:"path"`
+	URL        string   `json:"url"`
+	AheadBy    int      `json:"ahead_by"`
+	Commits    []string `json:"commit_messages"`
+	Added      int      `json:"additions"`
+	Deleted    int      `json:"deletions"`
+	Patch      string   `json:"patch,omitempty"`
+	Recomputed bool     `json:"recomputed_patch,omitempty"`
 }
 
 type jsonPatchGroup struct {
-	Patch    string   `json:"patch"`
-	ForkCount int     `json:"fork_count"`
-	Forks    []string `json:"forks"`
+	Patch     string          `json:"patch"`
+	ForkCount int             `json:"fork_count"`
+	Forks     []string        `json:"forks"`
+	Origin    *jsonOrigin     `json:"origin,omitempty"`
+	Upstream  bool            `json:"already_upstream"`
+	Includes  []jsonInclusion `json:"includes,omitempty"`
+	Partial   int             `json:"partial_support,omitempty"`
+}
+
+type jsonInclusion struct {
+	Forks      []string `json:"forks"`
+	ForkCount  int      `json:"fork_count"`
+	ExtraLines int      `json:"extra_lines"`
+}
+
+type jsonFamily struct {
+	Representative string        `json:"representative"`
+	Core           []string      `json:"core"`
+	Variants       []jsonVariant `json:"variants"`
+}
+
+type jsonVariant struct {
+	Forks []string `json:"forks"`
+	Extra []string `json:"extra"`
+}
+
+type jsonOrigin struct {
+	Author     string            `json:"author"`
+	Fork       string            `json:"fork"`
+	Date       time.Time         `json:"date"`
+	CopiedFrom map[string]string `json:"copied_from,omitempty"`
 }
 
 func PrintJSON(result *analysis.AnalysisResult) error {
@@ -55,52 +136,151 @@ func PrintJSON(result *analysis.AnalysisResult) error {
 		TotalForks: result.TotalForks,
 		Analyzed:   result.AnalyzedForks,
 		Active:     result.ActiveForks,
+		Partial:    result.Partial(),
+		NotReached: result.NotReached,
 	}
 
 	for _, rec := range analysis.Recommend(result) {
+		var votes []jsonVote
+		for _, h := range rec.HunkVotes {
+			var owners []string
+			for _, f := range h.Forks {
+				owners = append(owners, f.Label())
+			}
+			votes = append(votes, jsonVote{
+				Patch:    h.Hunk.String(),
+				Votes:    h.Convergence,
+				RawVotes: h.RawConvergence,
+				Of:       rec.Convergence,
+				Forks:    owners,
+				Included: h.Assembled,
+			})
+		}
 		out.RecommendedChanges = append(out.RecommendedChanges, jsonRecommendation{
 			File:          rec.File,
-			Patch:         fmt.Sprintf("--- a/%s\n+++ b/%s\n%s", rec.File, rec.File, rec.Patch),
+			Status:        rec.Status,
+			Patch:         rec.ApplicablePatch().String(),
+			Applies:       string(rec.Applies),
 			Convergence:   rec.Convergence,
+			Raw:           rec.RawConvergence,
 			AgreedBy:      rec.AgreedBy,
 			Forks:         rec.Forks,
 			CommitMessage: rec.CommitMessage,
+			PullRequests:  jsonPRs(rec.PullRequests),
+			HunkVotes:     votes,
 		})
 	}
 
 	for _, c := range result.Clusters {
 		jc := jsonCluster{
-			File:        c.Filename,
-			Convergence: c.Convergence,
+			File:         c.Filename,
+			Convergence:  c.Convergence,
+			Raw:          c.RawConvergence,
+			PullRequests: jsonPRs(c.PullRequests),
 		}
 		for _, f := range c.Forks {
 			jc.Forks = append(jc.Forks, jsonFork{
-				Owner:   f.Owner,
-				URL:     f.HTMLURL,
-				AheadBy: f.AheadBy,
-				Commits: f.CommitMessages,
-				Added:   f.Additions,
-				Deleted: f.Deletions,
-				Patch:   f.Patch,
+				Owner:      f.Owner,
+				Lineage:    f.Lineage,
+				Branch:     f.Branch,
+				Status:     f.Status,
+				Path:       f.Path,
+				URL:        f.HTMLURL,
+				AheadBy:    f.AheadBy,
+				Commits:    f.CommitMessages,
+				Added:      f.Additions,
+				Deleted:    f.Deletions,
+				Patch:      f.Patch.Body(),
+				Recomputed: f.Recomputed,
 			})
 		}
 		if c.PatchGroups != nil {
 			for _, g := range c.PatchGroups.Groups {
 				var owners []string
 				for _, f := range g.Forks {
-					owners = append(owners, f.Owner)
+					owners = append(owners, f.Label())
 				}
-				jc.PatchGroups = append(jc.PatchGroups, jsonPatchGroup{
-					Patch:     g.Full,
+				jg := jsonPatchGroup{
+					Patch:     g.Patch.Body(),
 					ForkCount: len(g.Forks),
 					Forks:     owners,
-				})
+					Upstream:  g.AlreadyUpstream,
+					Partial:   g.PartialSupport,
+				}
+				for _, inc := range g.Includes {
+					var included []string
+					for _, f := range c.PatchGroups.Groups[inc.Group].Forks {
+						included = append(included, f.Label())
+					}
+					jg.Includes = append(jg.Includes, jsonInclusion{Forks: included, ForkCount: len(included), ExtraLines: inc.Extra})
+				}
+				if o := g.Origin; o != nil {
+					jg.Origin = &jsonOrigin{Author: o.Author, Fork: o.Fork, Date: o.Date, CopiedFrom: o.CopiedFrom}
+				}
+				jc.PatchGroups = append(jc.PatchGroups, jg)
+			}
+			for _, fam := range c.PatchGroups.Families {
+				jf := jsonFamily{
+					Representative: c.PatchGroups.Groups[fam.Members[0]].Patch.Body(),
+					Core:           jsonLines(fam.Core),
+				}
+				for j, m := range fam.Members {
+					var owners []string
+					for _, f := range c.PatchGroups.Groups[m].Forks {
+						owners = append(owners, f.Label())
+					}
+					jf.Variants = append(jf.Variants, jsonVariant{Forks: owners, Extra: jsonLines(fam.Extra[j])})
+				}
+				jc.Families = append(jc.Families, jf)
+			}
+		}
+		for _, h := range c.Hunks {
+			var owners []string
+			for _, f := range h.Forks {
+				owners = append(owners, f.Label())
 			}
+			jc.Hunks = append(jc.Hunks, jsonHunk{
+				OldStart:    h.Hunk.OldStart,
+				OldLines:    h.Hunk.OldLines,
+				Patch:       h.Hunk.String(),
+				Convergence: h.Convergence,
+				Raw:         h.RawConvergence,
+				Forks:       owners,
+				Assembled:   h.Assembled,
+			})
 		}
 		out.Clusters = append(out.Clusters, jc)
 	}
 
+	for _, s := range result.Skipped {
+		out.Skipped = append(out.Skipped, jsonSkipped{Owner: s.Owner, Reason: s.Reason, Error: s.Error})
+	}
+
 	enc := json.NewEncoder(os.Stdout)
 	enc.SetIndent("", "  ")
 	return enc.Encode(out)
 }
+
+func jsonPRs(prs []analysis.LinkedPR) []jsonPR {
+	var out []jsonPR
+	for _, pr := range prs {
+		out = append(out, jsonPR{
+			Number: pr.Number,
+			Title:  pr.Title,
+			URL:    pr.URL,
+			Author: pr.Author,
+			State:  pr.State,
+			Forks:  pr.Forks,
+		})
+	}
+	return out
+}
+
+// jsonLines renders diff lines with their +/- markers.
+func jsonLines(lines []diff.Line) []string {
+	out := []string{}
+	for _, l := range lines {
+		out = append(out, string(l.Kind)+l.Text)
+	}
+	return out
+}
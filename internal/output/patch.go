@@ -2,24 +2,35 @@ package output
 
 import (
 	"fmt"
-	"strings"
+	"os"
 
 	"github.com/stympy/forkwatch/internal/analysis"
+	"github.com/stympy/forkwatch/internal/diff"
 )
 
 // PrintPatch emits a combined unified diff suitable for `git apply`.
-// It selects the most-converged-upon patch for each file cluster.
+// It selects the most-converged-upon patch for each file cluster, keeping
+// only the hunks that still apply to upstream.
 func PrintPatch(result *analysis.AnalysisResult) {
-	recs := analysis.Recommend(result)
-	for i, rec := range recs {
-		if i > 0 {
+	if result.Partial() {
+		// Keep stdout a valid diff; the warning goes to stderr.
+		fmt.Fprintf(os.Stderr, "Warning: partial results, %d forks not reached\n", result.NotReached)
+	}
+	first := true
+	for _, rec := range analysis.Recommend(result) {
+		patch := rec.ApplicablePatch()
+		if patch == nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s, no longer applies to upstream\n", rec.File)
+			continue
+		}
+		if rec.Applies == diff.ApplyConflicts {
+			fmt.Fprintf(os.Stderr, "Warning: %s: leaving out hunks that no longer apply to upstream\n", rec.File)
+		}
+		if !first {
 			// blank line between file diffs
 			fmt.Println()
 		}
-		fmt.Printf("--- a/%s\n", rec.File)
-		fmt.Printf("+++ b/%s\n", rec.File)
-		// The GitHub API patch already contains @@ hunk headers and
-		// diff lines; print it as-is.
-		fmt.Println(strings.TrimRight(rec.Patch, "\n"))
+		first = false
+		fmt.Print(patch.String())
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/stympy/forkwatch/internal/analysis"
+	"github.com/stympy/forkwatch/internal/diff"
 )
 
 const (
@@ -21,47 +22,97 @@ func PrintTable(result *analysis.AnalysisResult) {
 	fmt.Printf("\n%s%s%s/%s%s\n", colorBold, colorCyan, result.UpstreamOwner, result.UpstreamRepo, colorReset)
 	fmt.Printf("%sForks: %d total, %d analyzed, %d with meaningful changes%s\n\n",
 		colorDim, result.TotalForks, result.AnalyzedForks, result.ActiveForks, colorReset)
+	if result.Partial() {
+		fmt.Printf("%s%sPartial results: run interrupted with %d forks not reached%s\n\n",
+			colorBold, colorYellow, result.NotReached, colorReset)
+	}
 
 	if len(result.Clusters) == 0 {
 		fmt.Println("No meaningful fork activity found.")
+		printSkipped(result.Skipped)
 		return
 	}
 
 	// Show convergence clusters
 	for _, cluster := range result.Clusters {
-		convergenceLabel := ""
-		if cluster.Convergence >= 2 {
-			convergenceLabel = fmt.Sprintf(" %s%s(%d forks converge here)%s",
-				colorBold, colorYellow, cluster.Convergence, colorReset)
-		}
-
-		fmt.Printf("%s%s%s%s\n", colorBold, cluster.Filename, colorReset, convergenceLabel)
+		fmt.Printf("%s%s%s%s\n", colorBold, cluster.Filename, colorReset, convergenceLabel(cluster))
+		printPullRequests(cluster.PullRequests)
 
 		if cluster.PatchGroups != nil && len(cluster.PatchGroups.Groups) > 0 {
 			printPatchGroups(cluster)
+			printHunks(cluster)
 		} else {
 			printForkList(cluster.Forks)
 		}
 
 		fmt.Println(strings.Repeat("─", 60))
 	}
+
+	printSkipped(result.Skipped)
+}
+
+// convergenceLabel counts the forks touching a file. Forks that share
+// commits are one lineage; when some do, both counts are shown.
+func convergenceLabel(cluster analysis.FileCluster) string {
+	switch {
+	case cluster.RawConvergence < 2:
+		return ""
+	case cluster.Convergence == cluster.RawConvergence:
+		return fmt.Sprintf(" %s%s(%d forks converge here)%s",
+			colorBold, colorYellow, cluster.Convergence, colorReset)
+	case cluster.Convergence >= 2:
+		return fmt.Sprintf(" %s%s(%d independent forks converge here, %d in total)%s",
+			colorBold, colorYellow, cluster.Convergence, cluster.RawConvergence, colorReset)
+	default:
+		return fmt.Sprintf(" %s(%d related forks, sharing commits)%s",
+			colorDim, cluster.RawConvergence, colorReset)
+	}
+}
+
+func printPullRequests(prs []analysis.LinkedPR) {
+	for _, pr := range prs {
+		fmt.Printf("  %sPR #%d (%s) by %s: %s — from %s%s\n",
+			colorYellow, pr.Number, pr.State, pr.Author, pr.Title, strings.Join(pr.Forks, ", "), colorReset)
+	}
+}
+
+func printSkipped(skipped []analysis.SkippedFork) {
+	if len(skipped) == 0 {
+		return
+	}
+	fmt.Printf("\n%sSkipped %d forks:%s\n", colorBold, len(skipped), colorReset)
+	for _, s := range skipped {
+		fmt.Printf("  %s%-20s%s %s%s%s\n", colorCyan, s.Owner, colorReset, colorDim, s.Reason, colorReset)
+	}
 }
 
 func printPatchGroups(cluster analysis.FileCluster) {
 	for i, group := range cluster.PatchGroups.Groups {
+		if fam := cluster.PatchGroups.Family(i); fam != nil {
+			// Families are shown in full where their largest group would be.
+			if fam.Members[0] == i {
+				printFamily(cluster.PatchGroups, fam)
+			}
+			continue
+		}
 		if len(group.Forks) > 1 {
 			// Multi-fork group: show the shared diff then list owners
-			if i == 0 {
-				fmt.Printf("\n  %sMost common change pattern:%s\n", colorBold, colorReset)
-			} else {
-				fmt.Printf("\n  %sShared by %d forks:%s\n", colorDim, len(group.Forks), colorReset)
+			switch {
+			case group.AlreadyUpstream:
+				fmt.Printf("\n  %sShared by %d forks, already upstream:%s\n", colorDim, len(group.Forks), colorReset)
+			case i == 0:
+				fmt.Printf("\n  %sMost common change pattern:%s%s\n", colorBold, colorReset, applyNote(group.Application))
+			default:
+				fmt.Printf("\n  %sShared by %d forks:%s%s\n", colorDim, len(group.Forks), colorReset, applyNote(group.Application))
 			}
 			printDiff(group.Patch)
 			var owners []string
 			for _, f := range group.Forks {
-				owners = append(owners, f.Owner)
+				owners = append(owners, f.Label())
 			}
 			fmt.Printf("  %s%s%s\n", colorCyan, strings.Join(owners, ", "), colorReset)
+			printOrigin(group.Origin)
+			printInclusions(cluster.PatchGroups, group)
 		} else {
 			// Single-fork: show owner, stats, and their diff
 			f := group.Forks[0]
@@ -73,29 +124,162 @@ func printPatchGroups(cluster analysis.FileCluster) {
 				}
 				msg = " — " + msg
 			}
-			fmt.Printf("\n  %s%s%s %s+%d%s %s-%d%s%s\n",
-				colorCyan, f.Owner, colorReset,
+			fmt.Printf("\n  %s%s%s %s+%d%s %s-%d%s%s%s\n",
+				colorCyan, f.Label(), colorReset,
 				colorGreen, f.Additions, colorReset,
 				colorRed, f.Deletions, colorReset,
-				msg)
+				forkNotes(f), msg)
+			if group.AlreadyUpstream {
+				fmt.Printf("    %salready upstream%s\n", colorDim, colorReset)
+			}
 			printDiff(group.Patch)
+			printInclusions(cluster.PatchGroups, group)
+		}
+	}
+}
+
+// printInclusions notes the smaller changes a group's patch contains, such
+// as the same fix without a fork's extra lines.
+func printInclusions(grouping *analysis.PatchGrouping, group analysis.PatchGroup) {
+	for _, inc := range group.Includes {
+		forks := grouping.Groups[inc.Group].Forks
+		from := fmt.Sprintf("%d forks", len(forks))
+		if len(forks) == 1 {
+			from = forks[0].Label()
+		}
+		lines := "lines"
+		if inc.Extra == 1 {
+			lines = "line"
+		}
+		fmt.Printf("  %sincludes the change from %s plus %d extra %s%s\n", colorDim, from, inc.Extra, lines, colorReset)
+	}
+}
+
+// printFamily shows a family of similar patches: the lines they all change,
+// then what each variant changes beyond them.
+func printFamily(grouping *analysis.PatchGrouping, fam *analysis.PatchFamily) {
+	forks := 0
+	for _, m := range fam.Members {
+		forks += len(grouping.Groups[m].Forks)
+	}
+	fmt.Printf("\n  %sSimilar changes from %d forks, in common:%s\n", colorBold, forks, colorReset)
+	printLines(fam.Core)
+	for j, m := range fam.Members {
+		group := grouping.Groups[m]
+		var owners []string
+		for _, f := range group.Forks {
+			owners = append(owners, f.Label())
+		}
+		note := applyNote(group.Application)
+		if group.AlreadyUpstream {
+			note = fmt.Sprintf(" %s(already upstream)%s", colorDim, colorReset)
+		}
+		fmt.Printf("  %s%s%s%s\n", colorCyan, strings.Join(owners, ", "), colorReset, note)
+		if len(fam.Extra[j]) == 0 {
+			fmt.Printf("    %snothing else%s\n", colorDim, colorReset)
 		}
+		printLines(fam.Extra[j])
 	}
 }
 
-func printDiff(patch string) {
-	if patch == "" {
+// printHunks lists the single changes several forks make to the file,
+// including ones they make alongside different changes elsewhere in it,
+// and how the recommended ones, together, apply to upstream.
+func printHunks(cluster analysis.FileCluster) {
+	header := false
+	for _, h := range cluster.Hunks {
+		if h.RawConvergence < 2 {
+			continue
+		}
+		if !header {
+			note := ""
+			if g := cluster.HunkGroup; g != nil {
+				note = applyNote(g.Application)
+				if g.AlreadyUpstream {
+					note = fmt.Sprintf(" %s(recommended hunks already upstream)%s", colorDim, colorReset)
+				}
+			}
+			fmt.Printf("\n  %sHunks shared across forks:%s%s\n", colorBold, colorReset, note)
+			header = true
+		}
+		note := ""
+		if h.Assembled {
+			note = fmt.Sprintf(" %s(recommended)%s", colorGreen, colorReset)
+		}
+		fmt.Printf("\n  %s@@ -%d,%d @@ %d of %d forks%s%s\n",
+			colorDim, h.Hunk.OldStart, h.Hunk.OldLines, h.Convergence, cluster.Convergence, colorReset, note)
+		printDiff(&diff.Patch{Hunks: []diff.Hunk{h.Hunk}})
+		var owners []string
+		for _, f := range h.Forks {
+			owners = append(owners, f.Label())
+		}
+		fmt.Printf("  %s%s%s\n", colorCyan, strings.Join(owners, ", "), colorReset)
+	}
+}
+
+// applyNote says how well a shared patch applies to upstream today.
+func applyNote(a *diff.Application) string {
+	if a == nil {
+		return ""
+	}
+	switch a.Status {
+	case diff.ApplyClean:
+		return fmt.Sprintf(" %s(applies cleanly)%s", colorGreen, colorReset)
+	case diff.ApplyFuzzed:
+		return fmt.Sprintf(" %s(applies with fuzz)%s", colorYellow, colorReset)
+	default:
+		return fmt.Sprintf(" %s(%d hunks conflict with upstream)%s", colorRed, a.Failed, colorReset)
+	}
+}
+
+// printOrigin credits the first author of a shared change, and lists who
+// copied it from whom.
+func printOrigin(origin *analysis.Origin) {
+	if origin == nil {
 		return
 	}
-	diff := analysis.TruncateDiff(patch, 10)
-	for _, line := range strings.Split(diff, "\n") {
+	fmt.Printf("  %sfirst written by %s in %s on %s%s\n",
+		colorDim, origin.Author, origin.Fork, origin.Date.Format("2006-01-02"), colorReset)
+	var copies []string
+	for _, label := range origin.Copies() {
+		copies = append(copies, fmt.Sprintf("%s from %s", label, origin.CopiedFrom[label]))
+	}
+	if len(copies) > 0 {
+		fmt.Printf("  %scopied: %s%s\n", colorDim, strings.Join(copies, ", "), colorReset)
+	}
+}
+
+// maxDiffLines caps how much of each diff the table shows.
+const maxDiffLines = 10
+
+// printDiff shows the changed and context lines of a patch, without hunk
+// headers, capped at maxDiffLines.
+func printDiff(patch *diff.Patch) {
+	if patch.Empty() {
+		return
+	}
+	var lines []diff.Line
+	for _, h := range patch.Hunks {
+		lines = append(lines, h.Lines...)
+	}
+	printLines(lines)
+}
+
+// printLines shows diff lines, capped at maxDiffLines.
+func printLines(lines []diff.Line) {
+	for i, line := range lines {
+		if i == maxDiffLines {
+			fmt.Printf("    %s... (%d more lines)%s\n", colorDim, len(lines)-maxDiffLines, colorReset)
+			break
+		}
 		color := colorDim
-		if strings.HasPrefix(line, "+") {
+		switch line.Kind {
+		case diff.Added:
 			color = colorGreen
-		} else if strings.HasPrefix(line, "-") {
+		case diff.Deleted:
 			color = colorRed
 		}
-		fmt.Printf("    %s%s%s\n", color, line, colorReset)
+		fmt.Printf("    %s%c%s%s\n", color, line.Kind, line.Text, colorReset)
 	}
 }
 
@@ -105,8 +289,8 @@ func printForkList(forks []analysis.ForkSummary) {
 			colorGreen, fork.Additions, colorReset,
 			colorRed, fork.Deletions, colorReset)
 
-		fmt.Printf("  %s%-20s%s %s (%d commits ahead)\n",
-			colorCyan, fork.Owner, colorReset, stats, fork.AheadBy)
+		fmt.Printf("  %s%-20s%s %s (%d commits ahead)%s\n",
+			colorCyan, fork.Label(), colorReset, stats, fork.AheadBy, forkNotes(fork))
 
 		if len(fork.CommitMessages) > 0 {
 			msg := fork.CommitMessages[0]
@@ -119,3 +303,25 @@ func printForkList(forks []analysis.ForkSummary) {
 		fmt.Printf("    %s%s%s\n", colorDim, fork.HTMLURL, colorReset)
 	}
 }
+
+// forkNotes describes how a fork changed the file, beyond its line counts:
+// creating, deleting or renaming it, and whether forkwatch computed the
+// diff itself because GitHub didn't return it.
+func forkNotes(f analysis.ForkSummary) string {
+	var notes []string
+	switch f.Status {
+	case "added":
+		notes = append(notes, "new file")
+	case "removed":
+		notes = append(notes, "deleted")
+	case "renamed":
+		notes = append(notes, "renamed to "+f.Path)
+	}
+	if f.Recomputed {
+		notes = append(notes, "diff computed locally")
+	}
+	if len(notes) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" %s(%s)%s", colorDim, strings.Join(notes, ", "), colorReset)
+}
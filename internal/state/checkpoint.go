@@ -0,0 +1,178 @@
+package state
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	gh "github.com/stympy/forkwatch/internal/github"
+)
+
+// CheckpointHeader describes an analysis run: everything needed to carry
+// on comparing the same forks with the same options.
+type CheckpointHeader struct {
+	Version        int           `json:"version"`
+	Owner          string        `json:"owner"`
+	Repo           string        `json:"repo"`
+	UpstreamBranch string        `json:"upstream_branch"`
+	TotalForks     int           `json:"total_forks"`
+	MinAhead       int           `json:"min_ahead"`
+	Branches       []string      `json:"branches"`
+	Forks          []gh.ForkInfo `json:"forks"`
+}
+
+// CheckpointRecord is the outcome of comparing one fork.
+type CheckpointRecord struct {
+	Index       int                  `json:"index"` // position in CheckpointHeader.Forks
+	Comparisons []*gh.ForkComparison `json:"comparisons,omitempty"`
+	Reason      string               `json:"reason,omitempty"` // set when the fork was skipped
+	Error       string               `json:"error,omitempty"`
+}
+
+// Checkpoint is an append-only log of a run's progress: a header line
+// followed by one line per completed fork. Appending keeps each write small
+// and means an interrupted run loses at most the fork being written.
+type Checkpoint struct {
+	Header CheckpointHeader
+	Done   map[int]CheckpointRecord
+
+	path string
+	mu   sync.Mutex
+	f    *os.File
+}
+
+// CheckpointPath returns the checkpoint file for a repository under dir.
+func CheckpointPath(dir, owner, repo string) string {
+	return filepath.Join(dir, owner, repo+".jsonl")
+}
+
+// CreateCheckpoint starts a new checkpoint at path, replacing any previous one.
+func CreateCheckpoint(path string, header CheckpointHeader) (*Checkpoint, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint: %w", err)
+	}
+	header.Version = version
+	c := &Checkpoint{Header: header, Done: make(map[int]CheckpointRecord), path: path, f: f}
+	if err := c.write(header); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// NewMemoryCheckpoint returns a checkpoint that isn't written anywhere, for
+// runs without a cache directory.
+func NewMemoryCheckpoint(header CheckpointHeader) *Checkpoint {
+	header.Version = version
+	return &Checkpoint{Header: header, Done: make(map[int]CheckpointRecord)}
+}
+
+// OpenCheckpoint loads the checkpoint at path to resume it.
+func OpenCheckpoint(path string) (*Checkpoint, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0o644)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no interrupted run to resume (%s not found)", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open checkpoint: %w", err)
+	}
+
+	c := &Checkpoint{Done: make(map[int]CheckpointRecord), path: path, f: f}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 256<<20)
+	if !scanner.Scan() {
+		f.Close()
+		return nil, fmt.Errorf("checkpoint %s is empty", path)
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &c.Header); err != nil || c.Header.Version != version {
+		f.Close()
+		return nil, fmt.Errorf("checkpoint %s is unreadable or from another version of forkwatch", path)
+	}
+	for scanner.Scan() {
+		var rec CheckpointRecord
+		// A partial last line from an interrupted write is ignored; that
+		// fork is simply compared again.
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			break
+		}
+		c.Done[rec.Index] = rec
+	}
+	if err := scanner.Err(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	// Rewrite the file so a discarded partial line doesn't corrupt appends.
+	if err := c.rewrite(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Record appends the outcome for one fork.
+func (c *Checkpoint) Record(rec CheckpointRecord) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Done[rec.Index] = rec
+	return c.write(rec)
+}
+
+// Close closes the checkpoint file, leaving it in place for --resume.
+func (c *Checkpoint) Close() error {
+	if c.f == nil {
+		return nil
+	}
+	return c.f.Close()
+}
+
+// Remove closes and deletes the checkpoint once the run has finished.
+func (c *Checkpoint) Remove() error {
+	if c.f == nil {
+		return nil
+	}
+	c.f.Close()
+	return os.Remove(c.path)
+}
+
+func (c *Checkpoint) write(v any) error {
+	if c.f == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := c.f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (c *Checkpoint) rewrite() error {
+	if err := c.f.Truncate(0); err != nil {
+		return fmt.Errorf("failed to rewrite checkpoint: %w", err)
+	}
+	if _, err := c.f.Seek(0, 0); err != nil {
+		return fmt.Errorf("failed to rewrite checkpoint: %w", err)
+	}
+	if err := c.write(c.Header); err != nil {
+		return err
+	}
+	for i := range c.Header.Forks {
+		if rec, ok := c.Done[i]; ok {
+			if err := c.write(rec); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
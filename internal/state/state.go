@@ -0,0 +1,140 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	gh "github.com/stympy/forkwatch/internal/github"
+)
+
+// version is bumped whenever the file layout changes; files written by
+// other versions are discarded on load.
+const version = 6
+
+// Entry is the last set of comparisons made for a fork, one per branch
+// with meaningful changes.
+type Entry struct {
+	PushedAt    time.Time            `json:"pushed_at"`
+	Comparisons []*gh.ForkComparison `json:"comparisons"`
+}
+
+// Store persists fork comparisons between runs so that forks which haven't
+// been pushed to since the last run need not be compared again.
+type Store struct {
+	path string
+	mu   sync.Mutex
+
+	Version        int              `json:"version"`
+	UpstreamBranch string           `json:"upstream_branch"`
+	UpstreamHead   string           `json:"upstream_head"`
+	Branches       string           `json:"branches"` // --branches patterns the comparisons were made with
+	Forks          map[string]Entry `json:"forks"`
+}
+
+// Path returns the state file for a repository under dir.
+func Path(dir, owner, repo string) string {
+	return filepath.Join(dir, owner, repo+".json")
+}
+
+// Load reads the store at path. A missing file yields an empty store.
+func Load(path string) (*Store, error) {
+	s := &Store{path: path, Version: version, Forks: make(map[string]Entry)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", path, err)
+	}
+	if s.Version != version || s.Forks == nil {
+		*s = Store{path: path, Version: version, Forks: make(map[string]Entry)}
+	}
+	return s, nil
+}
+
+// Save writes the store back to its file.
+func (s *Store) Save() error {
+	s.mu.Lock()
+	data, err := json.Marshal(s)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// Lookup returns the stored comparisons for a fork if the fork hasn't been
+// pushed to since it was recorded.
+func (s *Store) Lookup(fork gh.ForkInfo) (Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.Forks[key(fork)]
+	if !ok || !e.PushedAt.Equal(fork.PushedAt.Time) {
+		return Entry{}, false
+	}
+	return e, true
+}
+
+// Record stores the comparisons made for a fork.
+func (s *Store) Record(fork gh.ForkInfo, comps []*gh.ForkComparison) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Forks[key(fork)] = Entry{PushedAt: fork.PushedAt.Time, Comparisons: comps}
+}
+
+// Reset discards all stored comparisons.
+func (s *Store) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Forks = make(map[string]Entry)
+}
+
+// Rebase records a new upstream head. Stored comparisons containing any of
+// upstreamCommits — the commits upstream gained since the recorded head —
+// are discarded, since upstream has absorbed part of the fork and its merge
+// base has moved.
+func (s *Store) Rebase(head string, upstreamCommits []string) {
+	landed := make(map[string]bool, len(upstreamCommits))
+	for _, sha := range upstreamCommits {
+		landed[sha] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.UpstreamHead = head
+	for k, e := range s.Forks {
+		if absorbed(e, landed) {
+			delete(s.Forks, k)
+		}
+	}
+}
+
+func absorbed(e Entry, landed map[string]bool) bool {
+	for _, comp := range e.Comparisons {
+		for _, sha := range comp.CommitSHAs {
+			if landed[sha] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func key(fork gh.ForkInfo) string {
+	return fork.Owner + "/" + fork.Repo
+}